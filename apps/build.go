@@ -1,13 +1,16 @@
 package apps
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 
 	"github.com/B9O2/bake/core"
+	"github.com/B9O2/bake/core/cache"
 	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
 	"github.com/B9O2/bake/utils"
 
 	"github.com/B9O2/Inspector/decorators"
@@ -17,50 +20,173 @@ import (
 
 type BuildApp struct {
 	*tabby.BaseApplication
-	ma *MainApp
+	ma      *MainApp
+	summary *Summary
 }
 
-func (ba *BuildApp) BuildOne(shadowBasePath string, pair recipe.BuildPair, cfg recipe.Config) error {
+// PrepareGroup 为一组影子项目特征相同的编译目标准备一份影子项目
+func PrepareGroup(shadowBasePath string, pairs []recipe.BuildPair, cfg recipe.Config, c *cache.Cache) (*core.GoBuilder, error) {
+	filter, err := core.NewCopyFilter(".", cfg.Copy, cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	rule, builder := pairs[0].Rule, pairs[0].Builder
+	b, err := core.NewGoProjectBuilder(shadowBasePath, ".", builder.GoPath(), filter, cfg.Debug)
+	if err != nil {
+		return nil, err
+	}
+	b.SetCache(c)
+
+	Insp.Print(Text("Shadow Project"), Path(b.ShadowPath()), Text(fmt.Sprintf("shared by %d pairs", len(pairs)), decorators.Cyan))
+	if err = b.ModEdit(pairs[0].Mod); err != nil {
+		return b, err
+	}
+	if err = b.GoVendor(rule.DependencyReplace); err != nil {
+		return b, err
+	}
+
+	if err = b.ModuleReplace(rule.ModuleReplace, cfg.Entrance, pairs); err != nil {
+		return b, err
+	}
+
+	if err = b.Inject(pairs[0].Inject); err != nil {
+		return b, err
+	}
+
+	if _, err = b.FileReplace(pairs[0]); err != nil {
+		return b, err
+	}
+
+	if err = b.Generate(pairs[0]); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// BuildRecipe 准备所有影子项目并执行检查，检查全部通过后再依次编译
+func (ba *BuildApp) BuildRecipe(shadowBasePath, recipeName string, cfg recipe.Config, c *cache.Cache) {
 	if cfg.Debug {
 		Insp.Print(LEVEL_INFO, Text("DEV MODE", decorators.Red))
 	}
-
-	b, err := core.NewGoProjectBuilder(shadowBasePath, ".", pair.Builder.Path, cfg.Debug)
-	if err != nil {
-		return err
+	fail := func(pairs []recipe.BuildPair, err error) {
+		for _, pair := range pairs {
+			ba.summary.Add(PairResult{Recipe: recipeName, Tag: pair.Tag(), Target: pair.Remote.Info(), Err: err})
+		}
 	}
 
+	groups := cfg.Groups()
+	builders := make([]*core.GoBuilder, len(groups))
 	defer func() {
-		if err = b.Close(); err != nil {
-			Insp.Print(LEVEL_WARNING, Error(err), Path(b.ShadowPath()), Text("not clean"))
-		} else {
-			//Insp.Print(LEVEL_INFO, Path(b.ShadowPath()), Text("cleaned"))
+		for _, b := range builders {
+			if b == nil {
+				continue
+			}
+			if err := b.Close(); err != nil {
+				Insp.Print(LEVEL_WARNING, Error(err), Path(b.ShadowPath()), Text("not clean"))
+			}
 		}
 	}()
 
-	//Insp.Print(Text("Shadow Project"), Path(b.ShadowPath()))
-	if err = b.GoVendor(pair.Rule.DependencyReplace); err != nil {
-		Insp.Print(Error(err))
-		return err
+	for i, pairs := range groups {
+		b, err := PrepareGroup(shadowBasePath, pairs, cfg, c)
+		if err != nil {
+			//影子项目准备失败时组内所有编译目标都记为失败
+			Insp.Print(Error(err))
+			fail(pairs, err)
+			if b != nil {
+				_ = b.Close()
+			}
+			continue
+		}
+		builders[i] = b
 	}
 
-	if err = b.FileReplace(pair.Rule.ReplacementWords, pair.Rule.Range); err != nil {
-		Insp.Print(Error(err))
-		return err
+	//任一检查失败时不编译任何目标
+	if len(cfg.Checks) > 0 {
+		passed := true
+		for _, b := range builders {
+			if b == nil {
+				continue
+			}
+			for _, check := range b.RunChecks(cfg.Checks) {
+				ba.summary.AddCheck(recipeName, check)
+				if check.Err != nil {
+					passed = false
+				}
+			}
+		}
+		if !passed {
+			err := errors.New("pre-build checks failed")
+			Insp.Print(Error(err), Text(recipeName, decorators.Magenta))
+			for i, b := range builders {
+				if b != nil {
+					fail(groups[i], err)
+				}
+			}
+			return
+		}
 	}
 
-	realOutput, err := b.BuildProject(pair.Builder.Args, cfg.Entrance, cfg.Output, pair)
+	for i, b := range builders {
+		if b == nil {
+			continue
+		}
+		for _, pair := range groups[i] {
+			Insp.Print(Text("Build Pair"), Text(pair.Tag(), decorators.Yellow), Text("<"+pair.Remote.Info()+">", decorators.Magenta))
+			result := PairResult{Recipe: recipeName, Tag: pair.Tag(), Target: pair.Remote.Info()}
+			result.Err = ba.BuildOne(b, pair, cfg, &result)
+			ba.summary.Add(result)
+			if result.Err != nil {
+				Insp.Print(Error(result.Err))
+				continue
+			}
+		}
+	}
+}
+
+// BuildOne 在已准备好的影子项目中编译单个目标并处理输出
+func (ba *BuildApp) BuildOne(b *core.GoBuilder, pair recipe.BuildPair, cfg recipe.Config, result *PairResult) error {
+	defer b.Release()
+	br, err := b.BuildProject(cfg.Entrance, cfg.Output, pair)
 	if err != nil {
 		return err
 	}
-	Insp.Print(Text("Build Successfully", decorators.Green), Text(realOutput))
+	result.Output, result.GoVersion, result.Cached = br.Output, br.GoVersion, br.Cached
+	Insp.Print(Text("Build Successfully", decorators.Green), Text(br.Output))
+
+	//另存符号后发布去除符号的产物
+	if pair.Output.DebugSymbols == options.DebugSymbolsSeparate {
+		if result.BuildID, err = core.SeparateSymbols(pair, cfg.Output, br.Output); err != nil {
+			return err
+		}
+	}
+
+	//检查产物，未通过时不压缩也不上传
+	if err = b.VerifyBinary(pair, br); err != nil {
+		return err
+	}
+	for _, f := range br.Files {
+		if err = core.VerifyOutput(pair.Verify, f, false, ""); err != nil {
+			return err
+		}
+	}
 
 	//Zip
 	if !pair.Output.Zip.IsEmpty() {
-		source := filepath.Join(cfg.Output, pair.Output.Zip.Source)
 		dest := filepath.Join(cfg.Output, pair.Output.Zip.Dest)
-		Insp.Print(Text("Zipping Output", decorators.Yellow), Text(fmt.Sprintf("%s -> %s", source, dest), decorators.Magenta))
-		if err = utils.Zip(source, dest, pair.Output.Zip.Password); err != nil {
+		if pair.Output.Zip.Source == "" {
+			//未设置source时压缩产物与附带文件
+			Insp.Print(Text("Zipping Output", decorators.Yellow), Text(fmt.Sprintf("%d files -> %s", len(br.Files), dest), decorators.Magenta))
+			err = utils.ZipFiles(br.Files, dest, pair.Output.Zip.Password)
+		} else {
+			source := filepath.Join(cfg.Output, pair.Output.Zip.Source)
+			Insp.Print(Text("Zipping Output", decorators.Yellow), Text(fmt.Sprintf("%s -> %s", source, dest), decorators.Magenta))
+			err = utils.Zip(source, dest, pair.Output.Zip.Password)
+		}
+		if err != nil {
+			return err
+		}
+		if err = core.VerifyOutput(pair.Verify, dest, true, pair.Output.Zip.Password); err != nil {
 			return err
 		}
 
@@ -75,6 +201,9 @@ func (ba *BuildApp) BuildOne(shadowBasePath string, pair recipe.BuildPair, cfg r
 	//SSH
 	if !pair.Output.SSH.IsEmpty() {
 		source := filepath.Join(cfg.Output, pair.Output.SSH.Source)
+		if pair.Output.SSH.Source == "" {
+			source = br.Output
+		}
 		Insp.Print(Text("SFTP", decorators.Yellow), Text(source, decorators.Magenta), Text("->", decorators.Yellow), Text(pair.Output.SSH.Dest, decorators.Magenta))
 		client := utils.NewSSHClient(pair.Output.SSH.Host, pair.Output.SSH.Port, &utils.SSHAuthConfig{
 			User:               pair.Output.SSH.User,
@@ -89,7 +218,16 @@ func (ba *BuildApp) BuildOne(shadowBasePath string, pair recipe.BuildPair, cfg r
 			return err
 		}
 
-		if utils.IsDir(source) {
+		if pair.Output.SSH.Source == "" {
+			//未设置source时dest为远程目录，上传产物与附带文件
+			for _, f := range br.Files {
+				remote := path.Join(pair.Output.SSH.Dest, filepath.Base(f))
+				Insp.Print(Text("Uploading File", decorators.Yellow), Text(f, decorators.Magenta), Text("->", decorators.Yellow), Text(remote, decorators.Magenta))
+				if err = client.UploadFile(f, remote); err != nil {
+					return err
+				}
+			}
+		} else if utils.IsDir(source) {
 			err = client.UploadDir(source, pair.Output.SSH.Dest)
 			if err != nil {
 				return err
@@ -104,7 +242,7 @@ func (ba *BuildApp) BuildOne(shadowBasePath string, pair recipe.BuildPair, cfg r
 		Insp.Print(Text("SFTP Successfully", decorators.Green), Text(pair.Output.SSH.Dest, decorators.Magenta))
 	}
 
-	return nil
+	return b.RunHooks(pair, core.HookPostOutput, br.Output)
 }
 
 func (ba *BuildApp) Detail() (string, string) {
@@ -117,30 +255,36 @@ func (ba *BuildApp) Init(ma tabby.Application) error {
 }
 
 func (ba *BuildApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	ba.summary = &Summary{}
 	shadowBasePath := path.Join(os.TempDir(), "BAKE_TMP")
 	Insp.Print(Text("TempDir", decorators.Magenta), Path(shadowBasePath))
 	for _, r := range args.AppPath()[1:] { //跳过根应用
 		Insp.Print(Text("Follow Recipe"), Text(r, decorators.Magenta))
-		config, err := recipe.LoadConfig(ba.ma.GetRecipePath(), r)
+		config, err := recipe.LoadConfig(ba.ma.GetRecipePath(), r, args.Get("brand").(string))
 		if err != nil {
 			return nil, err
 		}
 		Insp.Print(Text("Entrance"), Text(config.Entrance, decorators.Blue))
-		for _, pair := range config.Targets {
-			Insp.Print(Text("Build Pair"), Text(pair.Tag(), decorators.Yellow), Text("<"+pair.Remote.Info()+">", decorators.Magenta))
-			err = ba.BuildOne(shadowBasePath, pair, config)
-			if err != nil {
-				Insp.Print(Error(err))
-				continue
+
+		var c *cache.Cache
+		if !args.Get("no-cache").(bool) {
+			if c, err = openCache(config.Cache); err != nil {
+				return nil, err
 			}
 		}
+		ba.BuildRecipe(shadowBasePath, r, config, c)
 	}
+	ba.summary.Print()
 	Insp.Print(Text("Finished", decorators.Magenta))
 	return nil, nil
 }
 func NewBuildApp() *BuildApp {
-	return &BuildApp{
+	app := &BuildApp{
 		tabby.NewBaseApplication(false, nil),
 		nil,
+		&Summary{},
 	}
+	app.SetParam("no-cache", "Build every pair without the build cache", tabby.Bool(false))
+	app.SetParam("brand", "Apply this brand instead of the brand set in the recipe", tabby.String(""))
+	return app
 }
@@ -0,0 +1,182 @@
+package apps
+
+import (
+	"fmt"
+
+	"github.com/B9O2/bake/core/cache"
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+	"github.com/B9O2/bake/core/targets"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/B9O2/tabby"
+)
+
+// openCache 根据配置打开本地构建缓存
+func openCache(opt options.OptionCache) (*cache.Cache, error) {
+	maxSize, err := opt.MaxSizeBytes()
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewCache(opt.Dir, maxSize)
+}
+
+type CacheApp struct {
+	*tabby.BaseApplication
+	ma *MainApp
+}
+
+func (ca *CacheApp) Detail() (string, string) {
+	return "cache", "Show the local build cache"
+}
+
+func (ca *CacheApp) Init(ma tabby.Application) error {
+	ca.ma = ma.(*MainApp)
+	return nil
+}
+
+// Open 打开配置文件中设置的缓存，没有配置文件时使用默认设置
+func (ca *CacheApp) Open() (*cache.Cache, error) {
+	opt := options.OptionCache{}
+	if doc, err := recipe.LoadRecipeDoc(ca.ma.GetRecipePath()); err == nil {
+		opt = doc.Cache
+	}
+	return openCache(opt)
+}
+
+func (ca *CacheApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	c, err := ca.Open()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	Insp.Print(Text("Cache Dir"), Path(c.Dir()))
+	Insp.Print(Text("Artifacts"), Text(fmt.Sprint(len(entries)), decorators.Cyan))
+	Insp.Print(Text("Size"), Text(utils.FormatSize(total), decorators.Cyan), Text("/"), Text(utils.FormatSize(c.MaxSize()), decorators.Magenta))
+	return nil, nil
+}
+
+func NewCacheApp() *CacheApp {
+	ca := &CacheApp{}
+	ca.BaseApplication = tabby.NewBaseApplication(false, []tabby.Application{NewPruneCacheApp(ca), NewRemoteCacheApp(ca)})
+	return ca
+}
+
+type PruneCacheApp struct {
+	*tabby.BaseApplication
+	ca *CacheApp
+}
+
+func (pca *PruneCacheApp) Detail() (string, string) {
+	return "prune", "Remove least recently used artifacts beyond the cache size limit"
+}
+
+func (pca *PruneCacheApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	c, err := pca.ca.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Get("all").(bool) {
+		if err = c.Clear(); err != nil {
+			return nil, err
+		}
+		Insp.Print(Text("Cache Cleared", decorators.Green), Path(c.Dir()))
+		return nil, nil
+	}
+
+	maxSize := c.MaxSize()
+	if s := args.Get("max-size").(string); s != "" {
+		if maxSize, err = utils.ParseSize(s); err != nil {
+			return nil, err
+		}
+	}
+	removed, freed, err := c.Prune(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	Insp.Print(Text("Cache Pruned", decorators.Green), Text(fmt.Sprintf("%d artifacts", removed), decorators.Cyan), Text(utils.FormatSize(freed)+" freed", decorators.Cyan))
+	return nil, nil
+}
+
+func NewPruneCacheApp(ca *CacheApp) *PruneCacheApp {
+	app := &PruneCacheApp{
+		tabby.NewBaseApplication(false, nil),
+		ca,
+	}
+	app.SetParam("all", "Remove all cached artifacts", tabby.Bool(false), "a")
+	app.SetParam("max-size", "Prune down to this size instead of cache.max_size", tabby.String(""))
+	return app
+}
+
+type RemoteCacheApp struct {
+	*tabby.BaseApplication
+	ca *CacheApp
+}
+
+func (rca *RemoteCacheApp) Detail() (string, string) {
+	return "remote", "Show or clear the persistent Go caches on Docker and SSH targets of a recipe"
+}
+
+func (rca *RemoteCacheApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	recipeName := args.Get("recipe").(string)
+	clearCache := args.Get("clear").(bool)
+	config, err := recipe.LoadConfig(rca.ca.ma.GetRecipePath(), recipeName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	//同一目标只处理一次
+	visited := map[string]bool{}
+	for _, pair := range config.Targets {
+		t, ok := pair.Remote.(targets.GoCacheTarget)
+		if !ok || visited[t.Info()] {
+			continue
+		}
+		visited[t.Info()] = true
+
+		if err = t.InitAndConnect(utils.RandStr(12)); err != nil {
+			Insp.Print(Text(t.Info(), decorators.Magenta), Error(err))
+			continue
+		}
+		if clearCache {
+			if err = t.ClearGoCache(); err != nil {
+				Insp.Print(Text(t.Info(), decorators.Magenta), Error(err))
+			} else {
+				Insp.Print(Text(t.Info(), decorators.Magenta), Text("Go Cache Cleared", decorators.Green))
+			}
+		} else {
+			if info, err := t.GoCacheInfo(); err != nil {
+				Insp.Print(Text(t.Info(), decorators.Magenta), Error(err))
+			} else {
+				Insp.Print(Text(t.Info(), decorators.Magenta), Text(info, decorators.Cyan))
+			}
+		}
+		if err = t.Close(); err != nil {
+			Insp.Print(LEVEL_WARNING, Error(err))
+		}
+	}
+	if len(visited) == 0 {
+		Insp.Print(Text("No Docker or SSH targets in recipe", decorators.Yellow), Text(recipeName, decorators.Magenta))
+	}
+	return nil, nil
+}
+
+func NewRemoteCacheApp(ca *CacheApp) *RemoteCacheApp {
+	app := &RemoteCacheApp{
+		tabby.NewBaseApplication(false, nil),
+		ca,
+	}
+	app.SetParam("recipe", "Recipe whose targets are inspected", tabby.String("default"), "r")
+	app.SetParam("clear", "Clear the caches instead of showing them", tabby.Bool(false))
+	return app
+}
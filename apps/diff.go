@@ -0,0 +1,161 @@
+package apps
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/B9O2/bake/core"
+	"github.com/B9O2/bake/core/recipe"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/B9O2/tabby"
+)
+
+type DiffApp struct {
+	*tabby.BaseApplication
+	ma *MainApp
+}
+
+func (da *DiffApp) Detail() (string, string) {
+	return "diff", "Prepare the shadow project of a recipe and show its changes against the original project"
+}
+
+func (da *DiffApp) Init(ma tabby.Application) error {
+	da.ma = ma.(*MainApp)
+	return nil
+}
+
+// baseline 未经替换的影子项目，只执行go mod vendor，作为比较的原始项目
+func (da *DiffApp) baseline(shadowBasePath string, pair recipe.BuildPair, cfg recipe.Config) (*core.GoBuilder, error) {
+	filter, err := core.NewCopyFilter(".", cfg.Copy, cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	b, err := core.NewGoProjectBuilder(shadowBasePath, ".", pair.Builder.GoPath(), filter, false)
+	if err != nil {
+		return nil, err
+	}
+	return b, b.GoVendor(nil)
+}
+
+func (da *DiffApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	recipeName := argument(da, args, 0, "recipe")
+	if recipeName == "" {
+		recipeName = "default"
+	}
+	pairName := args.Get("pair").(string)
+	stat, files, keep := args.Get("stat").(bool), args.Get("files").(bool), args.Get("keep").(bool)
+
+	cfg, err := recipe.LoadConfig(da.ma.GetRecipePath(), recipeName, args.Get("brand").(string))
+	if err != nil {
+		return nil, err
+	}
+	//每份影子项目比较一次，指定编译目标时只比较该目标
+	var targets []recipe.BuildPair
+	for _, group := range cfg.Groups() {
+		if pairName == "" {
+			targets = append(targets, group[0])
+			continue
+		}
+		for _, pair := range group {
+			if pair.Pair() == pairName {
+				targets = append(targets, pair)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no pair %s in recipe %s", pairName, recipeName)
+	}
+
+	shadowBasePath := path.Join(os.TempDir(), "BAKE_TMP")
+	for _, pair := range targets {
+		Insp.Print(Text("Diff Pair"), Text(pair.Tag(), decorators.Yellow))
+		if err = da.diff(shadowBasePath, pair, cfg, stat, files, keep); err != nil {
+			Insp.Print(Text(pair.Tag(), decorators.Yellow), Error(err))
+		}
+	}
+	return nil, nil
+}
+
+func (da *DiffApp) diff(shadowBasePath string, pair recipe.BuildPair, cfg recipe.Config, stat, files, keep bool) error {
+	//预览时不保留开发模式的行为，由keep决定是否清理
+	cfg.Debug = false
+	var builders []*core.GoBuilder
+	defer func() {
+		for _, b := range builders {
+			if keep {
+				Insp.Print(Text("Shadow Kept", decorators.Yellow), Path(b.ShadowPath()))
+			} else if err := b.Close(); err != nil {
+				Insp.Print(LEVEL_WARNING, Error(err), Path(b.ShadowPath()), Text("not clean"))
+			}
+		}
+	}()
+
+	base, err := da.baseline(shadowBasePath, pair, cfg)
+	if base != nil {
+		builders = append(builders, base)
+	}
+	if err != nil {
+		return err
+	}
+	shadow, err := PrepareGroup(shadowBasePath, []recipe.BuildPair{pair}, cfg, nil)
+	if shadow != nil {
+		builders = append(builders, shadow)
+	}
+	if err != nil {
+		return err
+	}
+
+	changes, err := core.DiffTrees(base.ShadowPath(), shadow.ShadowPath(), core.VendorRenames(pair))
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		Insp.Print(Text("No Changes", decorators.Green))
+		return nil
+	}
+
+	switch {
+	case files:
+		for _, c := range changes {
+			if c.Status == core.ChangeRenamed {
+				fmt.Printf("%s\t%s -> %s\n", c.Status, c.Old, c.New)
+			} else {
+				fmt.Printf("%s\t%s\n", c.Status, c.Path())
+			}
+		}
+	case stat:
+		added, deleted := 0, 0
+		for _, c := range changes {
+			added += c.Added
+			deleted += c.Deleted
+			if c.Binary {
+				fmt.Printf(" %s | Bin\n", c.Path())
+			} else {
+				fmt.Printf(" %s | +%d -%d\n", c.Path(), c.Added, c.Deleted)
+			}
+		}
+		fmt.Printf(" %d files changed, %d insertions(+), %d deletions(-)\n", len(changes), added, deleted)
+	default:
+		for _, c := range changes {
+			fmt.Print(c.Diff)
+		}
+	}
+	return nil
+}
+
+func NewDiffApp() *DiffApp {
+	app := &DiffApp{
+		tabby.NewBaseApplication(false, nil),
+		nil,
+	}
+	app.SetParam("recipe", "Alias of the first argument: recipe to preview, default when empty", tabby.String(""), "r")
+	app.SetParam("pair", "Only preview this pair, e.g. linux/amd64 or linux/arm/v7", tabby.String(""), "p")
+	app.SetParam("stat", "Show changed files with inserted and deleted line counts", tabby.Bool(false))
+	app.SetParam("files", "Only list changed files", tabby.Bool(false))
+	app.SetParam("brand", "Apply this brand instead of the brand set in the recipe", tabby.String(""))
+	app.SetParam("keep", "Keep the shadow projects on disk for inspection", tabby.Bool(false))
+	return app
+}
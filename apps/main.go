@@ -33,6 +33,29 @@ func (ma *MainApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
 	return nil, nil
 }
 
+// positional 应用名之后的位置参数，与编译时的配置名相同从应用路径中读取
+func positional(app tabby.Application, args tabby.Arguments) []string {
+	name, _ := app.Detail()
+	appPath := args.AppPath()
+	for i, p := range appPath {
+		if p == name {
+			return appPath[i+1:]
+		}
+	}
+	return nil
+}
+
+// argument 第index个位置参数，flag作为别名优先
+func argument(app tabby.Application, args tabby.Arguments, index int, flag string) string {
+	if v := args.Get(flag).(string); v != "" {
+		return v
+	}
+	if pos := positional(app, args); index < len(pos) {
+		return pos[index]
+	}
+	return ""
+}
+
 func NewMainApp(version, recipePath string, subApps ...tabby.Application) *MainApp {
 	app := &MainApp{
 		tabby.NewBaseApplication(false, subApps),
@@ -0,0 +1,85 @@
+package apps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/B9O2/tabby"
+)
+
+type PlanApp struct {
+	*tabby.BaseApplication
+	ma *MainApp
+}
+
+func (pa *PlanApp) Detail() (string, string) {
+	return "plan", "Show the pairs of recipes and which when conditions matched, without building"
+}
+
+func (pa *PlanApp) Init(ma tabby.Application) error {
+	pa.ma = ma.(*MainApp)
+	return nil
+}
+
+func (pa *PlanApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	var names []string
+	if name := args.Get("recipe").(string); name != "" {
+		names = []string{name}
+	} else {
+		recipes, err := recipe.LoadAllRecipes(pa.ma.GetRecipePath())
+		if err != nil {
+			return nil, err
+		}
+		for name := range recipes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		cfg, err := recipe.LoadConfig(pa.ma.GetRecipePath(), name, args.Get("brand").(string))
+		if err != nil {
+			Insp.Print(Text("Recipe"), Text(name, decorators.Magenta), Error(err))
+			continue
+		}
+		Insp.Print(Text("Recipe"), Text(name, decorators.Magenta), Text(fmt.Sprintf("%d pairs", len(cfg.Targets)), decorators.Cyan))
+		if cfg.Brand != "" {
+			Insp.Print(Text("Brand"), Text(cfg.Brand, decorators.Magenta))
+		}
+		for i, pairs := range cfg.Groups() {
+			for _, pair := range pairs {
+				Insp.Print(Text(pair.Tag(), decorators.Yellow),
+					Text(strings.Join(pair.OutputFiles(), " ")),
+					Text(pair.Builder.BuilderKind(), decorators.Cyan),
+					Text(fmt.Sprintf("shadow#%d", i+1), decorators.Blue),
+					Text("<"+pair.Remote.Info()+">", decorators.Magenta))
+				if len(pair.Layers) > 0 {
+					Insp.Print(Text("  layers"), Text(strings.Join(pair.Layers, " > "), decorators.Blue))
+				}
+				for _, c := range pair.Conditions {
+					result := Text("matched", decorators.Green)
+					if !c.Matched {
+						result = Text("skipped", decorators.Red)
+					}
+					Insp.Print(Text("  when"), Text(c.Where, decorators.Blue), Text(c.Expr), result)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func NewPlanApp() *PlanApp {
+	app := &PlanApp{
+		tabby.NewBaseApplication(false, nil),
+		nil,
+	}
+	app.SetParam("recipe", "Only show this recipe", tabby.String(""), "r")
+	app.SetParam("brand", "Apply this brand instead of the brand set in the recipe", tabby.String(""))
+	return app
+}
@@ -0,0 +1,89 @@
+package apps
+
+import (
+	"fmt"
+
+	"github.com/B9O2/bake/core"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+)
+
+// PairResult 单个编译目标的结果
+type PairResult struct {
+	Recipe    string
+	Tag       string
+	Target    string
+	Output    string
+	GoVersion string
+	Cached    bool
+	BuildID   string //debug_symbols为separate时保存符号使用的Go build ID
+	Err       error
+}
+
+// CheckReport 一项构建前检查的结果
+type CheckReport struct {
+	Recipe string
+	core.CheckResult
+}
+
+// Summary 一次运行中所有编译目标的结果
+type Summary struct {
+	checks  []CheckReport
+	results []PairResult
+}
+
+func (s *Summary) Add(r PairResult) {
+	s.results = append(s.results, r)
+}
+
+func (s *Summary) AddCheck(recipe string, r core.CheckResult) {
+	s.checks = append(s.checks, CheckReport{recipe, r})
+}
+
+// Failed 失败的编译目标数量
+func (s *Summary) Failed() int {
+	failed := 0
+	for _, r := range s.results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (s *Summary) Print() {
+	for _, c := range s.checks {
+		name := Text(c.Recipe+"/check:"+c.Name, decorators.Yellow)
+		if c.Err != nil {
+			Insp.Print(name, Text("FAILED", decorators.Red), Path(c.Shadow), Text(c.Err.Error()))
+		} else {
+			Insp.Print(name, Text("OK", decorators.Green), Path(c.Shadow))
+		}
+		if c.Output != "" {
+			Insp.Print(Text(c.Output))
+		}
+	}
+	if len(s.results) == 0 {
+		return
+	}
+	Insp.Print(Text("Summary", decorators.Magenta), Text(fmt.Sprintf("%d succeeded, %d failed", len(s.results)-s.Failed(), s.Failed())))
+	for _, r := range s.results {
+		name := Text(r.Recipe+"/"+r.Tag, decorators.Yellow)
+		if r.Err != nil {
+			Insp.Print(name, Text("FAILED", decorators.Red), Text(r.Err.Error()))
+			continue
+		}
+		version := r.GoVersion
+		if r.Cached {
+			version = "cached"
+		} else if version == "" {
+			version = "unknown"
+		}
+		if r.BuildID != "" {
+			Insp.Print(name, Text("OK", decorators.Green), Text(r.Output), Text(version, decorators.Cyan), Text("<"+r.Target+">", decorators.Magenta), Text("symbols:"+r.BuildID, decorators.Blue))
+		} else {
+			Insp.Print(name, Text("OK", decorators.Green), Text(r.Output), Text(version, decorators.Cyan), Text("<"+r.Target+">", decorators.Magenta))
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package apps
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/B9O2/bake/core"
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/B9O2/tabby"
+)
+
+type SymbolizeApp struct {
+	*tabby.BaseApplication
+	ma *MainApp
+}
+
+func (sa *SymbolizeApp) Detail() (string, string) {
+	return "symbolize", "Map addresses in a stack trace back to functions and source lines using the saved symbols"
+}
+
+func (sa *SymbolizeApp) Init(ma tabby.Application) error {
+	sa.ma = ma.(*MainApp)
+	return nil
+}
+
+// symbolsDir 符号目录，未指定时使用配置的输出目录
+func (sa *SymbolizeApp) symbolsDir(args tabby.Arguments) (string, error) {
+	if dir := args.Get("dir").(string); dir != "" {
+		return dir, nil
+	}
+	cfg, err := recipe.LoadConfig(sa.ma.GetRecipePath(), args.Get("recipe").(string), "")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cfg.Output, core.SymbolsDir), nil
+}
+
+func (sa *SymbolizeApp) Main(args tabby.Arguments) (*tabby.TabbyContainer, error) {
+	buildID := argument(sa, args, 0, "id")
+	if buildID == "" {
+		return nil, errors.New("usage: bake symbolize <build ID or binary> [stack trace file]")
+	}
+	//也可以直接指定发布的产物，从中读取build ID
+	if ok, _ := utils.FileExists(buildID); ok && !utils.IsDir(buildID) {
+		id, err := core.ReadBuildID(buildID)
+		if err != nil {
+			return nil, err
+		}
+		buildID = id
+	}
+
+	dir, err := sa.symbolsDir(args)
+	if err != nil {
+		return nil, err
+	}
+	binaryPath, err := core.NewSymbolStore(dir).Find(buildID)
+	if err != nil {
+		return nil, err
+	}
+	table, err := core.LoadLineTable(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	Insp.Print(Text("Symbols"), Text(buildID, decorators.Cyan), Path(binaryPath))
+
+	var trace io.Reader = os.Stdin
+	if path := argument(sa, args, 1, "trace"); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		trace = f
+	}
+	return nil, core.Symbolize(table, trace, os.Stdout)
+}
+
+func NewSymbolizeApp() *SymbolizeApp {
+	app := &SymbolizeApp{
+		tabby.NewBaseApplication(false, nil),
+		nil,
+	}
+	app.SetParam("id", "Alias of the first argument: Go build ID, or the path of a released binary to read it from", tabby.String(""), "i")
+	app.SetParam("trace", "Alias of the second argument: stack trace file, read from stdin when empty", tabby.String(""), "t")
+	app.SetParam("recipe", "Recipe whose output directory holds the symbols", tabby.String("default"), "r")
+	app.SetParam("dir", "Symbols directory, overrides the recipe output directory", tabby.String(""))
+	return app
+}
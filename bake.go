@@ -23,7 +23,11 @@ func main() {
 	buildApp := apps.NewBuildApp()
 	initRecipeApp := apps.NewInitRecipeApp()
 	listRecipesApp := apps.NewListRecipesApp()
-	mainApp := apps.NewMainApp("main", "./RECIPE.toml", initRecipeApp, listRecipesApp)
+	cacheApp := apps.NewCacheApp()
+	planApp := apps.NewPlanApp()
+	diffApp := apps.NewDiffApp()
+	symbolizeApp := apps.NewSymbolizeApp()
+	mainApp := apps.NewMainApp("main", "./RECIPE.toml", initRecipeApp, listRecipesApp, cacheApp, planApp, diffApp, symbolizeApp)
 
 	t := tabby.NewTabby("Bake", mainApp)
 	t.SetUnknownApp(buildApp)
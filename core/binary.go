@@ -0,0 +1,348 @@
+package core
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"golang.org/x/mod/modfile"
+)
+
+// 产物格式
+const (
+	FormatELF   = "elf"
+	FormatPE    = "pe"
+	FormatMachO = "macho"
+	FormatWasm  = "wasm"
+	FormatAr    = "ar" //c-archive产生的静态库
+)
+
+// BinaryExpect 对产物的要求，为空的字段不检查
+type BinaryExpect struct {
+	Platform, Arch string
+	Static         bool
+	Stripped       bool
+	BuildInfo      bool //要求包含Go编译信息，wasm中无法读取编译信息，不检查
+	Archive        bool //c-archive产生的静态库
+	GoVersion      string
+	MainModule     string
+}
+
+// BinaryInfo 从产物中读取的信息
+type BinaryInfo struct {
+	Format     string
+	Static     bool
+	Stripped   bool
+	GoVersion  string //没有Go编译信息时为空
+	MainModule string
+	Skipped    []string //无法识别格式或架构而跳过的检查
+}
+
+// skip 记录跳过的检查
+func (bi *BinaryInfo) skip(format string, a ...any) {
+	bi.Skipped = append(bi.Skipped, fmt.Sprintf(format, a...))
+}
+
+// knownPlatform 平台是否有对应的产物格式
+func knownPlatform(platform string) bool {
+	for _, platforms := range formatPlatforms {
+		if slices.Contains(platforms, platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// 各格式支持的平台
+var formatPlatforms = map[string][]string{
+	FormatELF:   {"linux", "android", "freebsd", "netbsd", "openbsd", "dragonfly", "solaris", "illumos"},
+	FormatPE:    {"windows"},
+	FormatMachO: {"darwin", "ios"},
+	FormatWasm:  {"js", "wasip1"},
+}
+
+// 架构对应的ELF机器类型、位数与字节序
+var elfMachines = map[string]struct {
+	machine elf.Machine
+	class   elf.Class
+	order   elf.Data
+}{
+	"386":      {elf.EM_386, elf.ELFCLASS32, elf.ELFDATA2LSB},
+	"amd64":    {elf.EM_X86_64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"arm":      {elf.EM_ARM, elf.ELFCLASS32, elf.ELFDATA2LSB},
+	"arm64":    {elf.EM_AARCH64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"loong64":  {elf.EM_LOONGARCH, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"mips":     {elf.EM_MIPS, elf.ELFCLASS32, elf.ELFDATA2MSB},
+	"mipsle":   {elf.EM_MIPS, elf.ELFCLASS32, elf.ELFDATA2LSB},
+	"mips64":   {elf.EM_MIPS, elf.ELFCLASS64, elf.ELFDATA2MSB},
+	"mips64le": {elf.EM_MIPS, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"ppc64":    {elf.EM_PPC64, elf.ELFCLASS64, elf.ELFDATA2MSB},
+	"ppc64le":  {elf.EM_PPC64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"riscv64":  {elf.EM_RISCV, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"s390x":    {elf.EM_S390, elf.ELFCLASS64, elf.ELFDATA2MSB},
+}
+
+var peMachines = map[string]uint16{
+	"386":   pe.IMAGE_FILE_MACHINE_I386,
+	"amd64": pe.IMAGE_FILE_MACHINE_AMD64,
+	"arm":   pe.IMAGE_FILE_MACHINE_ARMNT,
+	"arm64": pe.IMAGE_FILE_MACHINE_ARM64,
+}
+
+var machoCpus = map[string]macho.Cpu{
+	"386":   macho.Cpu386,
+	"amd64": macho.CpuAmd64,
+	"arm":   macho.CpuArm,
+	"arm64": macho.CpuArm64,
+}
+
+// DetectFormat 根据文件头判断产物格式，无法识别时返回空
+func DetectFormat(r io.ReaderAt) string {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return ""
+	}
+	switch {
+	case bytes.Equal(magic, []byte("\x7fELF")):
+		return FormatELF
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return FormatPE
+	case bytes.Equal(magic, []byte("\x00asm")):
+		return FormatWasm
+	case bytes.Equal(magic, []byte("!<ar")):
+		return FormatAr
+	}
+	switch string(magic) {
+	case "\xfe\xed\xfa\xce", "\xfe\xed\xfa\xcf", "\xce\xfa\xed\xfe", "\xcf\xfa\xed\xfe":
+		return FormatMachO
+	}
+	return ""
+}
+
+// CheckBinary 解析产物并检查格式、平台、架构与编译信息是否符合要求。
+// 无法识别的格式、平台或架构不视为错误，跳过相应的检查并记录在BinaryInfo.Skipped中
+func CheckBinary(path string, expect BinaryExpect) (BinaryInfo, error) {
+	info := BinaryInfo{}
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	info.Format = DetectFormat(f)
+	if info.Format == "" {
+		//例如aix的XCOFF与plan9的a.out，只检查编译信息
+		info.skip("unrecognized binary format, format, platform and arch not checked")
+		if errs := checkBuildInfo(f, expect, &info); len(errs) > 0 {
+			return info, fmt.Errorf("%s: %w", path, errors.Join(errs...))
+		}
+		return info, nil
+	}
+	if info.Format == FormatAr {
+		//静态库由各平台的目标文件组成，不检查平台与编译信息
+		if !expect.Archive {
+			return info, fmt.Errorf("%s: unexpected static library", path)
+		}
+		return info, nil
+	}
+	if expect.Archive {
+		return info, fmt.Errorf("%s: %s binary is not a static library", path, info.Format)
+	}
+	if !knownPlatform(expect.Platform) {
+		info.skip("no known binary format for platform %s, platform not checked", expect.Platform)
+	} else if !slices.Contains(formatPlatforms[info.Format], expect.Platform) {
+		return info, fmt.Errorf("%s: %s binary does not match platform %s", path, info.Format, expect.Platform)
+	}
+
+	switch info.Format {
+	case FormatELF:
+		err = checkELF(f, expect, &info)
+	case FormatPE:
+		err = checkPE(f, expect, &info)
+	case FormatMachO:
+		err = checkMachO(f, expect, &info)
+	case FormatWasm:
+		if expect.Arch != "wasm" {
+			err = fmt.Errorf("wasm module does not match arch %s", expect.Arch)
+		}
+		//wasm没有符号表与动态链接的概念
+		info.Static, info.Stripped = true, true
+	}
+	if err != nil {
+		return info, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	if expect.Static && !info.Static {
+		errs = append(errs, errors.New("binary is dynamically linked"))
+	}
+	if expect.Stripped && !info.Stripped {
+		errs = append(errs, errors.New("binary has a symbol table"))
+	}
+	//debug/buildinfo不支持wasm
+	if info.Format != FormatWasm {
+		errs = append(errs, checkBuildInfo(f, expect, &info)...)
+	}
+	if len(errs) > 0 {
+		return info, fmt.Errorf("%s: %w", path, errors.Join(errs...))
+	}
+	return info, nil
+}
+
+// checkBuildInfo 读取编译信息并检查Go版本与主模块
+func checkBuildInfo(r io.ReaderAt, expect BinaryExpect, info *BinaryInfo) []error {
+	bi, err := buildinfo.Read(r)
+	if err == nil {
+		info.GoVersion, info.MainModule = bi.GoVersion, bi.Main.Path
+	}
+	var errs []error
+	switch {
+	case info.GoVersion == "" && info.Format == "":
+		//无法识别的格式可能不支持读取编译信息
+		info.skip("no readable Go build info, Go version and main module not checked")
+	case info.GoVersion == "":
+		if expect.BuildInfo || expect.GoVersion != "" || expect.MainModule != "" {
+			errs = append(errs, errors.New("no Go build info in binary"))
+		}
+	default:
+		if expect.GoVersion != "" && !matchGoVersion(info.GoVersion, expect.GoVersion) {
+			errs = append(errs, fmt.Errorf("built with %s, want %s", info.GoVersion, expect.GoVersion))
+		}
+		if expect.MainModule != "" && info.MainModule != expect.MainModule {
+			errs = append(errs, fmt.Errorf("main module is %s, want %s", info.MainModule, expect.MainModule))
+		}
+	}
+	return errs
+}
+
+// matchGoVersion want为"go1.22"时匹配go1.22与go1.22.x
+func matchGoVersion(version, want string) bool {
+	return version == want || strings.HasPrefix(version, want+".")
+}
+
+func checkELF(r io.ReaderAt, expect BinaryExpect, info *BinaryInfo) error {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return err
+	}
+	if want, ok := elfMachines[expect.Arch]; !ok {
+		info.skip("unknown ELF machine for arch %s, arch not checked", expect.Arch)
+	} else if f.Machine != want.machine || f.Class != want.class || f.Data != want.order {
+		return fmt.Errorf("%s %s %s binary does not match arch %s", f.Machine, f.Class, f.Data, expect.Arch)
+	}
+	if expect.Platform == "freebsd" && f.OSABI != elf.ELFOSABI_FREEBSD {
+		return fmt.Errorf("%s binary does not match platform freebsd", f.OSABI)
+	}
+	libs, err := f.ImportedLibraries()
+	if err != nil {
+		return err
+	}
+	hasInterp := false
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			hasInterp = true
+			break
+		}
+	}
+	info.Static = len(libs) == 0 && !hasInterp
+	info.Stripped = f.Section(".symtab") == nil
+	return nil
+}
+
+func checkPE(r io.ReaderAt, expect BinaryExpect, info *BinaryInfo) error {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return err
+	}
+	if want, ok := peMachines[expect.Arch]; !ok {
+		info.skip("unknown PE machine for arch %s, arch not checked", expect.Arch)
+	} else if f.Machine != want {
+		return fmt.Errorf("PE machine 0x%x does not match arch %s", f.Machine, expect.Arch)
+	}
+	//Windows程序总是动态链接系统DLL，不作为动态链接处理
+	info.Static = true
+	info.Stripped = len(f.Symbols) == 0
+	return nil
+}
+
+func checkMachO(r io.ReaderAt, expect BinaryExpect, info *BinaryInfo) error {
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return err
+	}
+	if want, ok := machoCpus[expect.Arch]; !ok {
+		info.skip("unknown Mach-O CPU for arch %s, arch not checked", expect.Arch)
+	} else if f.Cpu != want {
+		return fmt.Errorf("Mach-O %s binary does not match arch %s", f.Cpu, expect.Arch)
+	}
+	//macOS程序总是链接libSystem，不作为动态链接处理
+	info.Static = true
+	info.Stripped = true
+	if f.Symtab != nil {
+		for _, sym := range f.Symtab.Syms {
+			if sym.Sect != 0 { //只计算定义在产物中的符号，导入的符号在strip后仍然存在
+				info.Stripped = false
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// moduleName 影子项目go.mod中的模块路径
+func (gb *GoBuilder) moduleName() string {
+	data, err := os.ReadFile(filepath.Join(gb.shadowPath, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	return modfile.ModulePath(data)
+}
+
+// VerifyBinary 检查取回的产物是否与编译目标一致。go编译器还要求编译信息中的Go版本与主模块与影子项目一致
+func (gb *GoBuilder) VerifyBinary(pair recipe.BuildPair, br BuildResult) error {
+	verify := pair.Verify
+	if verify.SkipFormat {
+		return nil
+	}
+	expect := BinaryExpect{
+		Platform:   pair.Platform,
+		Arch:       pair.Arch,
+		Archive:    pair.Builder.BuildModeKind() == options.BuildModeCArchive,
+		Static:     verify.Static,
+		Stripped:   verify.Stripped,
+		GoVersion:  verify.GoVersion,
+		MainModule: verify.MainModule,
+	}
+	if pair.Builder.BuilderKind() == options.BuilderKindGo {
+		expect.BuildInfo = true
+		if expect.GoVersion == "" {
+			expect.GoVersion = br.GoVersion
+		}
+		if expect.MainModule == "" {
+			expect.MainModule = gb.moduleName()
+		}
+	}
+	info, err := CheckBinary(br.Output, expect)
+	for _, reason := range info.Skipped {
+		Insp.Print(LEVEL_WARNING, Text("Binary Check Skipped", decorators.Yellow), Text(pair.Tag(), decorators.Magenta), Text(reason))
+	}
+	if err != nil {
+		return err
+	}
+	Insp.Print(Text("Binary Verified", decorators.Green), Text(info.Format, decorators.Cyan), Text(pair.Tag(), decorators.Yellow), Text(info.GoVersion, decorators.Blue))
+	return nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := BinaryExpect{Platform: runtime.GOOS, Arch: runtime.GOARCH, BuildInfo: true, GoVersion: runtime.Version()}
+	info, err := CheckBinary(exe, expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format == "" || info.MainModule != "github.com/B9O2/bake" {
+		t.Errorf("info %+v", info)
+	}
+
+	other := expect
+	other.Arch = "s390x"
+	if runtime.GOARCH == "s390x" {
+		other.Arch = "amd64"
+	}
+	if _, err = CheckBinary(exe, other); err == nil {
+		t.Error("arch mismatch not reported")
+	}
+	other = expect
+	other.Platform = "windows"
+	if runtime.GOOS == "windows" {
+		other.Platform = "linux"
+	}
+	if _, err = CheckBinary(exe, other); err == nil {
+		t.Error("platform mismatch not reported")
+	}
+	//未知的架构与格式跳过检查而不是失败
+	unknown := expect
+	unknown.Arch = "sparc64"
+	if info, err = CheckBinary(exe, unknown); err != nil || len(info.Skipped) == 0 {
+		t.Errorf("unknown arch: skipped %v, err %v", info.Skipped, err)
+	}
+	unknown.Platform = "plan9"
+	if info, err = CheckBinary(exe, unknown); err != nil || len(info.Skipped) < 2 {
+		t.Errorf("unknown platform: skipped %v, err %v", info.Skipped, err)
+	}
+	data := filepath.Join(t.TempDir(), "app")
+	if err = os.WriteFile(data, []byte("\x00\x01\x02\x03 not a known format"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = CheckBinary(data, BinaryExpect{Platform: "aix", Arch: "ppc64"}); err != nil || len(info.Skipped) == 0 {
+		t.Errorf("unknown format: skipped %v, err %v", info.Skipped, err)
+	}
+
+	strict := expect
+	strict.GoVersion, strict.MainModule = "go1.1", "example.com/other"
+	_, err = CheckBinary(exe, strict)
+	if err == nil || !strings.Contains(err.Error(), "want go1.1") || !strings.Contains(err.Error(), "example.com/other") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/B9O2/bake/utils"
+)
+
+const (
+	artifactName  = "artifact"
+	companionsDir = "companions" //C头文件、wasm_exec.js等附带文件，按文件名保存
+)
+
+// Entry 缓存中的一个构建产物
+type Entry struct {
+	Key     string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Cache 以构建输入哈希为键的本地产物缓存
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+// Get 命中时将产物复制到dest，附带文件按文件名复制到companions中的路径，缺少任一文件时视为未命中
+func (c *Cache) Get(key, dest string, companions ...string) (bool, error) {
+	entryPath := c.entryPath(key)
+	sources := map[string]string{dest: filepath.Join(entryPath, artifactName)}
+	for _, companion := range companions {
+		sources[companion] = filepath.Join(entryPath, companionsDir, filepath.Base(companion))
+	}
+	stats := map[string]os.FileInfo{}
+	for target, src := range sources {
+		stat, err := os.Stat(src)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		stats[target] = stat
+	}
+	for target, src := range sources {
+		if err := utils.CopyFile(src, target, stats[target].Mode()); err != nil {
+			return false, err
+		}
+	}
+	//更新访问时间，淘汰时优先移除最久未使用的产物
+	now := time.Now()
+	_ = os.Chtimes(entryPath, now, now)
+	return true, nil
+}
+
+// Put 保存产物与附带文件，超出容量时淘汰最久未使用的产物
+func (c *Cache) Put(key, src string, companions ...string) error {
+	entryPath := c.entryPath(key)
+	tmpPath := entryPath + ".tmp" + utils.RandStr(6)
+	files := map[string]string{src: filepath.Join(tmpPath, artifactName)}
+	for _, companion := range companions {
+		files[companion] = filepath.Join(tmpPath, companionsDir, filepath.Base(companion))
+	}
+	for from, to := range files {
+		stat, err := os.Stat(from)
+		if err == nil {
+			err = utils.CopyFile(from, to, stat.Mode())
+		}
+		if err != nil {
+			_ = os.RemoveAll(tmpPath)
+			return err
+		}
+	}
+	_ = os.RemoveAll(entryPath)
+	if err := os.Rename(tmpPath, entryPath); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return err
+	}
+	_, _, err := c.Prune(c.maxSize)
+	return err
+}
+
+// Entries 列出所有缓存产物，按最近使用时间排序
+func (c *Cache) Entries() ([]Entry, error) {
+	var entries []Entry
+	shards, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() {
+			continue
+		}
+		items, err := os.ReadDir(filepath.Join(c.dir, shard.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			//跳过正在写入的临时目录
+			if strings.Contains(item.Name(), ".tmp") {
+				continue
+			}
+			entryPath := filepath.Join(c.dir, shard.Name(), item.Name())
+			info, err := item.Info()
+			if err != nil {
+				return nil, err
+			}
+			size, err := dirSize(entryPath)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, Entry{
+				Key:     item.Name(),
+				Path:    entryPath,
+				Size:    size,
+				ModTime: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.After(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Prune 淘汰最久未使用的产物直至总大小不超过maxSize，maxSize<=0时不做限制
+func (c *Cache) Prune(maxSize int64) (int, int64, error) {
+	if maxSize <= 0 {
+		return 0, 0, nil
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total, freed int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	removed := 0
+	//从最久未使用的产物开始淘汰
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		if err = os.RemoveAll(entries[i].Path); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += entries[i].Size
+		total -= entries[i].Size
+	}
+	return removed, freed, nil
+}
+
+// Clear 清空缓存目录
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) MaxSize() int64 {
+	return c.maxSize
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Hasher 计算构建输入的哈希
+type Hasher struct {
+	h hash.Hash
+}
+
+// Add 加入一组字符串输入
+func (kh *Hasher) Add(parts ...string) {
+	for _, part := range parts {
+		_, _ = io.WriteString(kh.h, part)
+		_, _ = kh.h.Write([]byte{0})
+	}
+}
+
+// AddTree 加入目录树，包括相对路径、权限与文件内容。skip返回true的目录与文件不参与计算
+func (kh *Hasher) AddTree(root string, skip func(rel string) bool) error {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && skip != nil && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, rel := range files {
+		if err = kh.AddFile(root, rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddFile 加入单个文件，rel为相对root的路径
+func (kh *Hasher) AddFile(root, rel string) error {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	kh.Add(rel, stat.Mode().String())
+	_, err = io.Copy(kh.h, f)
+	return err
+}
+
+func (kh *Hasher) Sum() string {
+	return hex.EncodeToString(kh.h.Sum(nil))
+}
+
+func NewHasher() *Hasher {
+	return &Hasher{h: sha256.New()}
+}
+
+// DefaultDir 默认缓存目录 ~/.cache/bake
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "bake"), nil
+}
+
+// NewCache 打开缓存目录，dir为空时使用默认目录
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	var err error
+	if dir == "" {
+		dir, err = DefaultDir()
+	} else {
+		dir, err = utils.ExpandHome(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maxSize < 0 {
+		return nil, errors.New("cache max size must not be negative")
+	}
+	if err = os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
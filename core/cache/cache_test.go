@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachePutGet(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCache(filepath.Join(dir, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "app")
+	if err = os.WriteFile(src, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHasher()
+	h.Add("go", "linux", "amd64")
+	key := h.Sum()
+
+	dest := filepath.Join(dir, "out", "app")
+	if hit, err := c.Get(key, dest); err != nil || hit {
+		t.Fatalf("unexpected hit=%v err=%v", hit, err)
+	}
+	if err = c.Put(key, src); err != nil {
+		t.Fatal(err)
+	}
+	if hit, err := c.Get(key, dest); err != nil || !hit {
+		t.Fatalf("expected hit, err=%v", err)
+	}
+	content, err := os.ReadFile(dest)
+	if err != nil || string(content) != "binary" {
+		t.Fatalf("bad artifact %q %v", content, err)
+	}
+
+	header := filepath.Join(dir, "app.h")
+	if err = os.WriteFile(header, []byte("extern void Run();"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Put(key, src, header); err != nil {
+		t.Fatal(err)
+	}
+	outHeader := filepath.Join(dir, "out", "app.h")
+	if hit, err := c.Get(key, dest, outHeader); err != nil || !hit {
+		t.Fatalf("expected hit with companion, err=%v", err)
+	}
+	if content, err = os.ReadFile(outHeader); err != nil || string(content) != "extern void Run();" {
+		t.Fatalf("bad companion %q %v", content, err)
+	}
+	if hit, err := c.Get(key, dest, filepath.Join(dir, "out", "wasm_exec.js")); err != nil || hit {
+		t.Fatalf("missing companion hit=%v err=%v", hit, err)
+	}
+
+	removed, _, err := c.Prune(1)
+	if err != nil || removed != 1 {
+		t.Fatalf("prune removed %d, err=%v", removed, err)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCache(filepath.Join(dir, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//由旧到新依次为10、100、10字节，只需淘汰最旧的一个即可低于上限
+	sizes := []int{10, 100, 10}
+	var keys []string
+	now := time.Now()
+	for i, size := range sizes {
+		src := filepath.Join(dir, "app")
+		if err = os.WriteFile(src, make([]byte, size), 0755); err != nil {
+			t.Fatal(err)
+		}
+		h := NewHasher()
+		h.Add(string(rune('a' + i)))
+		key := h.Sum()
+		if err = c.Put(key, src); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i-len(sizes)) * time.Minute)
+		if err = os.Chtimes(c.entryPath(key), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	//正在写入的临时目录不参与淘汰
+	tmp := c.entryPath(keys[0]) + ".tmpabcdef"
+	if err = os.MkdirAll(tmp, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := c.Prune(115)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || freed != 10 {
+		t.Fatalf("removed %d entries (%d bytes), want 1 (10 bytes)", removed, freed)
+	}
+	for i, key := range keys {
+		_, err := os.Stat(c.entryPath(key))
+		if exists := err == nil; exists != (i > 0) {
+			t.Errorf("entry %d exists %v", i, exists)
+		}
+	}
+	if _, err = os.Stat(tmp); err != nil {
+		t.Errorf("staging directory removed: %v", err)
+	}
+}
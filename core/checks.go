@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/kballard/go-shellquote"
+)
+
+// 内置检查
+const (
+	CheckVet     = "vet"
+	CheckTest    = "test"
+	CheckCommand = "cmd:" //自定义命令前缀，例如"cmd:golangci-lint run"
+)
+
+// CheckResult 一项检查的结果
+type CheckResult struct {
+	Name   string
+	Shadow string
+	Output string
+	Err    error
+}
+
+// checkCommand 解析检查项对应的命令
+func (gb *GoBuilder) checkCommand(check string) (string, []string, error) {
+	switch {
+	case check == CheckVet:
+		return gb.builderPath, []string{"vet", "./..."}, nil
+	case check == CheckTest:
+		return gb.builderPath, []string{"test", "./..."}, nil
+	case strings.HasPrefix(check, CheckCommand):
+		words, err := shellquote.Split(strings.TrimPrefix(check, CheckCommand))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(words) == 0 {
+			return "", nil, errors.New("empty check command")
+		}
+		return words[0], words[1:], nil
+	}
+	return "", nil, fmt.Errorf("unknown check '%s'", check)
+}
+
+// RunChecks 在替换后的影子项目中依次执行检查
+func (gb *GoBuilder) RunChecks(checks []string) []CheckResult {
+	var results []CheckResult
+	for _, check := range checks {
+		result := CheckResult{Name: check, Shadow: gb.shadowPath}
+		cmd, args, err := gb.checkCommand(check)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		Insp.Print(Text("Check", decorators.Yellow), Text(check, decorators.Cyan))
+		c := exec.Command(cmd, args...)
+		c.Dir = gb.shadowPath
+		output, err := c.CombinedOutput()
+		result.Output = strings.TrimSpace(string(output))
+		if err != nil {
+			result.Err = fmt.Errorf("check '%s' failed: %w", check, err)
+			Insp.Print(Text("Check Failed", decorators.Red), Text(check, decorators.Cyan))
+		} else {
+			Insp.Print(Text("Check Passed", decorators.Green), Text(check, decorators.Cyan))
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// 修改类型，与git diff --name-status一致
+const (
+	ChangeAdded    = "A"
+	ChangeModified = "M"
+	ChangeDeleted  = "D"
+	ChangeRenamed  = "R"
+)
+
+// FileChange 影子项目中相对原始项目的一个文件修改
+type FileChange struct {
+	Status   string
+	Old, New string //相对路径，新增时Old为空，删除时New为空
+	Binary   bool
+	Added    int //新增行数
+	Deleted  int //删除行数
+	Diff     string
+}
+
+// Path 修改后的路径，删除时为原路径
+func (fc FileChange) Path() string {
+	if fc.New != "" {
+		return fc.New
+	}
+	return fc.Old
+}
+
+// VendorRenames 编译目标的替换规则对vendor目录的重命名
+func VendorRenames(pair recipe.BuildPair) map[string]string {
+	renames := map[string]string{}
+	for old, dst := range pair.Rule.DependencyReplace {
+		renames["vendor/"+old] = "vendor/" + dst
+	}
+	for old, dst := range pair.Rule.ModuleReplace {
+		renames["vendor/"+old] = "vendor/" + dst
+	}
+	return renames
+}
+
+// listFiles 目录中所有普通文件的相对路径，跳过.git
+func listFiles(root string) (map[string]bool, error) {
+	files := map[string]bool{}
+	err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// DiffTrees 比较oldRoot与newRoot，renames中的目录(旧路径->新路径)重命名后的文件与原文件配对比较
+func DiffTrees(oldRoot, newRoot string, renames map[string]string) ([]FileChange, error) {
+	oldFiles, err := listFiles(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	//新路径对应的原路径
+	origin := func(rel string) string {
+		for old, dst := range renames {
+			if strings.HasPrefix(rel, dst+"/") {
+				candidate := old + strings.TrimPrefix(rel, dst)
+				if oldFiles[candidate] && !newFiles[candidate] {
+					return candidate
+				}
+			}
+		}
+		return rel
+	}
+
+	var changes []FileChange
+	matched := map[string]bool{}
+	for rel := range newFiles {
+		change := FileChange{New: rel, Old: origin(rel)}
+		b, err := os.ReadFile(filepath.Join(newRoot, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		var a []byte
+		if oldFiles[change.Old] {
+			matched[change.Old] = true
+			if a, err = os.ReadFile(filepath.Join(oldRoot, filepath.FromSlash(change.Old))); err != nil {
+				return nil, err
+			}
+			if change.Old != rel {
+				change.Status = ChangeRenamed
+			} else if bytes.Equal(a, b) {
+				continue
+			} else {
+				change.Status = ChangeModified
+			}
+		} else {
+			change.Old, change.Status = "", ChangeAdded
+		}
+		if err = change.diff(a, b); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	for rel := range oldFiles {
+		if matched[rel] || newFiles[rel] {
+			continue
+		}
+		a, err := os.ReadFile(filepath.Join(oldRoot, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		change := FileChange{Status: ChangeDeleted, Old: rel}
+		if err = change.diff(a, nil); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path() < changes[j].Path()
+	})
+	return changes, nil
+}
+
+// diff 生成统一格式的差异与增删行数
+func (fc *FileChange) diff(a, b []byte) error {
+	oldName, newName := "/dev/null", "/dev/null"
+	if fc.Old != "" {
+		oldName = "a/" + fc.Old
+	}
+	if fc.New != "" {
+		newName = "b/" + fc.New
+	}
+	oldPath, newPath := fc.Old, fc.New
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	if newPath == "" {
+		newPath = oldPath
+	}
+	header := "diff --git a/" + oldPath + " b/" + newPath + "\n"
+	if fc.Status == ChangeRenamed {
+		header += "rename from " + fc.Old + "\nrename to " + fc.New + "\n"
+	}
+	if utils.IsBinary(a) || utils.IsBinary(b) {
+		fc.Binary = true
+		fc.Diff = header + "Binary files " + oldName + " and " + newName + " differ\n"
+		return nil
+	}
+	if bytes.Equal(a, b) {
+		fc.Diff = header
+		return nil
+	}
+
+	aLines, bLines := splitLines(a), splitLines(b)
+	for _, op := range difflib.NewMatcherWithJunk(aLines, bLines, false, nil).GetOpCodes() {
+		switch op.Tag {
+		case 'r':
+			fc.Deleted += op.I2 - op.I1
+			fc.Added += op.J2 - op.J1
+		case 'd':
+			fc.Deleted += op.I2 - op.I1
+		case 'i':
+			fc.Added += op.J2 - op.J1
+		}
+	}
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        aLines,
+		B:        bLines,
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  3,
+	})
+	fc.Diff = header + text
+	return err
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	oldRoot, newRoot := t.TempDir(), t.TempDir()
+	writeTree(t, oldRoot, map[string]string{
+		"main.go":                 "package main\n\nconst host = \"google.com\"\n",
+		"same.go":                 "package main\n",
+		"removed.txt":             "bye\n",
+		"vendor/old.com/lib/a.go": "package lib\n",
+	})
+	writeTree(t, newRoot, map[string]string{
+		"main.go":                 "package main\n\nconst host = \"apple.com\"\n",
+		"same.go":                 "package main\n",
+		"gen.go":                  "package main\n",
+		"vendor/new.com/lib/a.go": "package lib\n",
+	})
+
+	changes, err := DiffTrees(oldRoot, newRoot, map[string]string{"vendor/old.com/lib": "vendor/new.com/lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Status+" "+c.Path())
+	}
+	want := "A gen.go,M main.go,D removed.txt,R vendor/new.com/lib/a.go"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("changes %v, want %s", got, want)
+	}
+	main := changes[1]
+	if main.Added != 1 || main.Deleted != 1 || !strings.Contains(main.Diff, "-const host = \"google.com\"\n+const host = \"apple.com\"\n") {
+		t.Errorf("main.go diff +%d -%d:\n%s", main.Added, main.Deleted, main.Diff)
+	}
+	if !strings.Contains(changes[3].Diff, "rename from vendor/old.com/lib/a.go") {
+		t.Errorf("rename not reported:\n%s", changes[3].Diff)
+	}
+}
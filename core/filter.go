@@ -0,0 +1,80 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe/options"
+	"github.com/B9O2/bake/utils"
+)
+
+// BakeIgnoreFile 项目根目录下的忽略文件，使用gitignore语法
+const BakeIgnoreFile = ".bakeignore"
+
+// CopyFilter 决定项目中哪些文件会被复制到影子项目
+type CopyFilter struct {
+	projectPath string
+	gitignore   bool
+	rules       *utils.IgnoreMatcher //默认规则、.gitignore与.bakeignore
+	overrides   *utils.IgnoreMatcher //copy.exclude与copy.include，优先级最高
+}
+
+// Skip 判断相对项目根目录的路径是否不需要复制
+func (cf *CopyFilter) Skip(rel string, isDir bool) bool {
+	if cf == nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	ignored, matched := cf.overrides.Check(rel, isDir)
+	if !matched {
+		ignored = cf.rules.Match(rel, isDir)
+	}
+	//进入子目录时读取其中的.gitignore
+	if isDir && !ignored && cf.gitignore {
+		_ = cf.rules.AddFile(filepath.Join(cf.projectPath, filepath.FromSlash(rel), ".gitignore"), rel)
+	}
+	return ignored
+}
+
+// NewCopyFilter 创建复制筛选器，输出目录位于项目内时总是被排除
+func NewCopyFilter(projectPath string, opt options.OptionCopy, output string) (*CopyFilter, error) {
+	projectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	cf := &CopyFilter{
+		projectPath: projectPath,
+		gitignore:   opt.GitIgnore,
+		rules:       utils.NewIgnoreMatcher(),
+		overrides:   utils.NewIgnoreMatcher(),
+	}
+
+	cf.rules.Add(".git/", "")
+	if opt.GitIgnore {
+		if err = cf.rules.AddFile(filepath.Join(projectPath, ".gitignore"), ""); err != nil {
+			return nil, err
+		}
+	}
+	if err = cf.rules.AddFile(filepath.Join(projectPath, BakeIgnoreFile), ""); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range opt.Exclude {
+		cf.overrides.Add(pattern, "")
+	}
+	for _, pattern := range opt.Include {
+		cf.overrides.Add("!"+strings.TrimPrefix(pattern, "!"), "")
+	}
+
+	//排除输出目录，避免之前的产物被复制进影子项目
+	if output != "" {
+		absOutput, err := filepath.Abs(output)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(projectPath, absOutput); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			cf.overrides.Add("/"+filepath.ToSlash(rel)+"/", "")
+		}
+	}
+	return cf, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/kballard/go-shellquote"
+)
+
+// Generate 替换完成后在影子项目中以编译目标的GOOS/GOARCH与编译环境变量执行go generate与生成命令
+func (gb *GoBuilder) Generate(pair recipe.BuildPair) error {
+	gen := pair.Generate
+	if gen.IsEmpty() {
+		return nil
+	}
+	env, err := pair.BuildEnv()
+	if err != nil {
+		return err
+	}
+	environ := pair.LocalTarget().ProcessEnv(env)
+
+	run := func(name string, args ...string) error {
+		Insp.Print(Text("Generate", decorators.Yellow), Text(pair.Tag(), decorators.Magenta), Text(shellquote.Join(append([]string{name}, args...)...)))
+		cmd := exec.Command(name, args...)
+		cmd.Dir = gb.shadowPath
+		cmd.Env = environ
+		output, err := cmd.CombinedOutput()
+		if len(output) > 0 {
+			Insp.Print(Text(strings.TrimSpace(string(output)), decorators.Cyan))
+		}
+		if err != nil {
+			return fmt.Errorf("generate '%s' failed: %w", name, err)
+		}
+		return nil
+	}
+
+	if args := gen.GenerateArgs(); args != nil {
+		if err = run(gb.builderPath, args...); err != nil {
+			return err
+		}
+	}
+	for _, command := range gen.Commands {
+		words, err := shellquote.Split(command)
+		if err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return errors.New("empty generate command")
+		}
+		if err = run(words[0], words[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+)
+
+func TestGenerate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("generate directives use cp")
+	}
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"go.mod": "module example.com/gen\n\ngo 1.21\n",
+		"main.go": "package main\n\n" +
+			"//go:generate cp main.go gen_${GOOS}_${GOARCH}.txt\n" +
+			"//go:generate cp main.go skipped.txt\n\n" +
+			"func main() {}\n",
+	})
+	gb := &GoBuilder{builderPath: "go", shadowPath: root}
+	//只设置run时对./...执行go generate
+	pair := recipe.BuildPair{Platform: "linux", Arch: "arm64", Generate: options.OptionGenerate{Run: "gen_"}}
+	if err := gb.Generate(pair); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "gen_linux_arm64.txt")); err != nil {
+		t.Errorf("generated with the pair's GOOS/GOARCH: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "skipped.txt")); err == nil {
+		t.Error("-run filter ignored")
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+)
+
+// 钩子阶段
+const (
+	HookPreBuild   = "pre_build"
+	HookPostBuild  = "post_build"
+	HookPostOutput = "post_output"
+)
+
+// hookEnv 钩子命令可以使用的环境变量
+func hookEnv(pair recipe.BuildPair, stage, output string) (map[string]string, error) {
+	env, err := pair.BuildEnv()
+	if err != nil {
+		return nil, err
+	}
+	env["BAKE_RECIPE"] = pair.Recipe
+	env["BAKE_BRAND"] = pair.Brand
+	env["BAKE_PLATFORM"] = pair.Platform
+	env["BAKE_ARCH"] = pair.Arch
+	env["BAKE_VARIANT"] = pair.Variant
+	env["BAKE_TAG"] = pair.Tag()
+	env["BAKE_NAME"] = pair.Name()
+	env["BAKE_STAGE"] = stage
+	//本地输出路径
+	env["BAKE_OUTPUT"] = output
+	//编译目标上影子项目中的输出路径
+	env["BAKE_SHADOW_OUTPUT"] = filepath.ToSlash(filepath.Join("shadow_bin", pair.Name()))
+	return env, nil
+}
+
+// RunHooks 在编译目标的影子项目目录中依次执行某一阶段的钩子，任一钩子失败时返回错误
+func (gb *GoBuilder) RunHooks(pair recipe.BuildPair, stage, output string) error {
+	var hooks []string
+	switch stage {
+	case HookPreBuild:
+		hooks = pair.Hooks.PreBuild
+	case HookPostBuild:
+		hooks = pair.Hooks.PostBuild
+	case HookPostOutput:
+		hooks = pair.Hooks.PostOutput
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if err := gb.connect(pair.Remote); err != nil {
+		return err
+	}
+	env, err := hookEnv(pair, stage, output)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		Insp.Print(Text("Hook "+stage, decorators.Yellow), Text(hook, decorators.Cyan))
+		stdout, stderr, err := pair.Remote.ExecShell(hook, env)
+		if out := strings.TrimSpace(string(stdout) + string(stderr)); out != "" {
+			Insp.Print(Text("Hook Output", decorators.Cyan), Text(out))
+		}
+		if err != nil {
+			return fmt.Errorf("hook %s '%s' failed: %w", stage, hook, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/B9O2/bake/core/recipe/options"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+)
+
+// injectFile 复制单个文件到影子项目，覆盖已存在的文件，rel为影子项目中的相对路径
+func (gb *GoBuilder) injectFile(src, rel string) error {
+	dest, err := utils.SafeJoin(gb.shadowPath, rel)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	//不通过已存在的符号链接写入
+	if linfo, err := os.Lstat(dest); err == nil && linfo.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(dest); err != nil {
+			return err
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return utils.CopyFile(src, dest, info.Mode().Perm())
+}
+
+// Inject 将inject.files与inject.dirs复制到影子项目，需在GoVendor之后执行
+func (gb *GoBuilder) Inject(inject options.OptionInject) error {
+	if inject.IsEmpty() {
+		return nil
+	}
+	if err := inject.Validate(gb.projectPath); err != nil {
+		return err
+	}
+
+	dirs := make([]string, 0, len(inject.Dirs))
+	for dest := range inject.Dirs {
+		dirs = append(dirs, dest)
+	}
+	sort.Strings(dirs)
+	for _, dest := range dirs {
+		src := inject.Source(gb.projectPath, inject.Dirs[dest])
+		err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			return gb.injectFile(path, filepath.Join(dest, rel))
+		})
+		if err != nil {
+			return err
+		}
+		Insp.Print(Text("Injected", decorators.Green), Path(dest), Text("<- "+inject.Dirs[dest], decorators.Cyan))
+	}
+
+	//单个文件在目录之后复制，可以覆盖目录中的同名文件
+	files := make([]string, 0, len(inject.Files))
+	for dest := range inject.Files {
+		files = append(files, dest)
+	}
+	sort.Strings(files)
+	for _, dest := range files {
+		if err := gb.injectFile(inject.Source(gb.projectPath, inject.Files[dest]), dest); err != nil {
+			return err
+		}
+		Insp.Print(Text("Injected", decorators.Green), Path(dest), Text("<- "+inject.Files[dest], decorators.Cyan))
+	}
+	return nil
+}
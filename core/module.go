@@ -0,0 +1,324 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"golang.org/x/mod/modfile"
+)
+
+// ModuleRenamer 按模块路径前缀重命名导入路径，较长的旧路径优先匹配
+type ModuleRenamer struct {
+	olds        []string
+	replacement map[string]string
+}
+
+// Rename 返回path重命名后的路径，不匹配任何旧路径时ok为false
+func (mr ModuleRenamer) Rename(path string) (string, bool) {
+	for _, old := range mr.olds {
+		if path == old {
+			return mr.replacement[old], true
+		}
+		if strings.HasPrefix(path, old+"/") {
+			return mr.replacement[old] + strings.TrimPrefix(path, old), true
+		}
+	}
+	return path, false
+}
+
+// RewriteImports 使用go/parser定位导入路径并替换，只修改导入声明中的字符串，返回修改过的文件数量
+func (mr ModuleRenamer) RewriteImports(root string) (int, error) {
+	changed := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "shadow_bin" || d.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		ok, err := mr.rewriteFile(path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			changed++
+		}
+		return nil
+	})
+	return changed, err
+}
+
+func (mr ModuleRenamer) rewriteFile(path string) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		//无法解析的文件保持原样，由之后的编译报告错误
+		return false, nil
+	}
+
+	//按偏移从后向前替换，保持其余内容与格式不变
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return false, err
+		}
+		if newPath, ok := mr.Rename(importPath); ok {
+			edits = append(edits, edit{
+				start: fset.Position(spec.Path.Pos()).Offset,
+				end:   fset.Position(spec.Path.End()).Offset,
+				text:  strconv.Quote(newPath),
+			})
+		}
+	}
+	if len(edits) == 0 {
+		return false, nil
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		src = append(src[:e.start], append([]byte(e.text), src[e.end:]...)...)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, src, info.Mode().Perm())
+}
+
+// RewriteGoMod 修改go.mod中的module、require与replace
+func (mr ModuleRenamer) RewriteGoMod(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return err
+	}
+	if f.Module != nil {
+		if newPath, ok := mr.Rename(f.Module.Mod.Path); ok {
+			if err = f.AddModuleStmt(newPath); err != nil {
+				return err
+			}
+		}
+	}
+	requires := append([]*modfile.Require(nil), f.Require...)
+	for _, r := range requires {
+		if newPath, ok := mr.Rename(r.Mod.Path); ok {
+			version, indirect := r.Mod.Version, r.Indirect
+			if err = f.DropRequire(r.Mod.Path); err != nil {
+				return err
+			}
+			f.AddNewRequire(newPath, version, indirect)
+		}
+	}
+	replaces := append([]*modfile.Replace(nil), f.Replace...)
+	for _, r := range replaces {
+		oldPath, oldRenamed := mr.Rename(r.Old.Path)
+		newPath, newRenamed := r.New.Path, false
+		if r.New.Version != "" { //本地路径替换不修改
+			newPath, newRenamed = mr.Rename(r.New.Path)
+		}
+		if !oldRenamed && !newRenamed {
+			continue
+		}
+		oldVersion, newVersion := r.Old.Version, r.New.Version
+		if err = f.DropReplace(r.Old.Path, oldVersion); err != nil {
+			return err
+		}
+		if err = f.AddReplace(oldPath, oldVersion, newPath, newVersion); err != nil {
+			return err
+		}
+	}
+	f.Cleanup()
+	data, err = f.Format()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, info.Mode().Perm())
+}
+
+// RewriteModulesTxt 修改vendor/modules.txt中的模块与包路径
+func (mr ModuleRenamer) RewriteModulesTxt(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "## "):
+			//模块属性，如"## explicit; go 1.21"
+		case strings.HasPrefix(line, "# "):
+			//模块行，如"# old v1.0.0"或"# old v1.0.0 => new v1.1.0"
+			fields := strings.Fields(line)
+			for i := 1; i < len(fields); i++ {
+				if i > 1 && fields[i-1] != "=>" {
+					continue
+				}
+				if strings.HasPrefix(fields[i], ".") || filepath.IsAbs(fields[i]) {
+					continue
+				}
+				fields[i], _ = mr.Rename(fields[i])
+			}
+			line = strings.Join(fields, " ")
+		default:
+			//包路径
+			line, _ = mr.Rename(line)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// MoveVendor 将vendor中旧模块的目录移动到新路径
+func (mr ModuleRenamer) MoveVendor(vendor string) error {
+	for _, old := range mr.olds {
+		src := filepath.Join(vendor, filepath.FromSlash(old))
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		dest := filepath.Join(vendor, filepath.FromSlash(mr.replacement[old]))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewModuleRenamer(replacement map[string]string) ModuleRenamer {
+	mr := ModuleRenamer{replacement: replacement}
+	for old := range replacement {
+		mr.olds = append(mr.olds, old)
+	}
+	sort.Slice(mr.olds, func(i, j int) bool {
+		if len(mr.olds[i]) != len(mr.olds[j]) {
+			return len(mr.olds[i]) > len(mr.olds[j])
+		}
+		return mr.olds[i] < mr.olds[j]
+	})
+	return mr
+}
+
+// ModuleReplace 在影子项目与vendor中重命名模块，需在GoVendor之后执行。完成后以各编译目标的环境变量编译入口，确认结果可用
+func (gb *GoBuilder) ModuleReplace(replacement map[string]string, entrance string, pairs []recipe.BuildPair) error {
+	if len(replacement) == 0 {
+		return nil
+	}
+	mr := NewModuleRenamer(replacement)
+	vendor := filepath.Join(gb.shadowPath, "vendor")
+
+	if err := mr.MoveVendor(vendor); err != nil {
+		return err
+	}
+	changed, err := mr.RewriteImports(gb.shadowPath)
+	if err != nil {
+		return err
+	}
+	if err = mr.RewriteGoMod(filepath.Join(gb.shadowPath, "go.mod")); err != nil {
+		return err
+	}
+	if err = mr.RewriteModulesTxt(filepath.Join(vendor, "modules.txt")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	Insp.Print(Text("Module Replaced", decorators.Green), Text(fmt.Sprintf("%d files", changed), decorators.Cyan))
+
+	//同一GOOS/GOARCH只需检查一次
+	checked := map[string]bool{}
+	for _, pair := range pairs {
+		target := pair.Platform + "/" + pair.Arch
+		if checked[target] {
+			continue
+		}
+		checked[target] = true
+		if err = gb.verifyModuleReplace(pair, entrance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyModuleReplace 以编译目标的环境变量与tags编译入口，产物丢弃
+func (gb *GoBuilder) verifyModuleReplace(pair recipe.BuildPair, entrance string) error {
+	env, err := pair.BuildEnv()
+	if err != nil {
+		return err
+	}
+	args := []string{"build", "-mod=vendor", "-o", os.DevNull}
+	if len(pair.Builder.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(pair.Builder.Tags, ","))
+	}
+	cmd := exec.Command(gb.builderPath, append(args, entrance)...)
+	cmd.Dir = gb.shadowPath
+	cmd.Env = pair.LocalTarget().ProcessEnv(env)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("module replace verification failed on %s/%s: %s %s", pair.Platform, pair.Arch, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ModEdit 以go mod edit修改影子项目的go.mod并下载修改后的依赖，需在GoVendor之前执行，原始项目不受影响
+func (gb *GoBuilder) ModEdit(mod options.OptionMod) error {
+	if mod.IsEmpty() {
+		return nil
+	}
+	args, err := mod.EditArgs(gb.projectPath)
+	if err != nil {
+		return err
+	}
+	commands := [][]string{args}
+	if download := mod.DownloadArgs(); download != nil {
+		commands = append(commands, download)
+	}
+	for _, cmdArgs := range commands {
+		cmd := exec.Command(gb.builderPath, cmdArgs...)
+		cmd.Dir = gb.shadowPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("go %s failed: %s %s", strings.Join(cmdArgs[:2], " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	Insp.Print(Text("go.mod Edited", decorators.Green), Text(strings.Join(args[2:], " "), decorators.Cyan))
+	return nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleRenamer(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                             "module example.com/app\n\ngo 1.21\n\nrequire (\n\tgithub.com/old/lib v1.2.0\n\tgithub.com/old/library v0.1.0\n)\n",
+		"main.go":                            "package main\n\nimport (\n\tlib \"github.com/old/lib/sub\"\n\t\"github.com/old/library\"\n)\n\n// github.com/old/lib stays in comments\nvar s = \"github.com/old/lib\"\n\nfunc main() { lib.F(); library.G() }\n",
+		"vendor/modules.txt":                 "# github.com/old/lib v1.2.0\n## explicit; go 1.21\ngithub.com/old/lib/sub\n# github.com/old/library v0.1.0\n## explicit\ngithub.com/old/library\n",
+		"vendor/github.com/old/lib/sub/a.go": "package sub\n\nimport _ \"github.com/old/lib/internal\"\n\nfunc F() {}\n",
+	}
+	writeTree(t, root, files)
+
+	mr := NewModuleRenamer(map[string]string{"github.com/old/lib": "github.com/new/lib"})
+	vendor := filepath.Join(root, "vendor")
+	if err := mr.MoveVendor(vendor); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := mr.RewriteImports(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("changed %d files, want 2", changed)
+	}
+	if err = mr.RewriteGoMod(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	if err = mr.RewriteModulesTxt(filepath.Join(vendor, "modules.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	main := readTree(t, root, "main.go")
+	if !strings.Contains(main, "lib \"github.com/new/lib/sub\"") || !strings.Contains(main, "\"github.com/old/library\"") {
+		t.Errorf("imports not rewritten:\n%s", main)
+	}
+	if !strings.Contains(main, "var s = \"github.com/old/lib\"") || !strings.Contains(main, "// github.com/old/lib stays") {
+		t.Errorf("non-import text rewritten:\n%s", main)
+	}
+	if !strings.Contains(readTree(t, root, "vendor/github.com/new/lib/sub/a.go"), "github.com/new/lib/internal") {
+		t.Error("vendored imports not rewritten")
+	}
+	mod := readTree(t, root, "go.mod")
+	if !strings.Contains(mod, "github.com/new/lib v1.2.0") || strings.Contains(mod, "github.com/old/lib ") || !strings.Contains(mod, "github.com/old/library v0.1.0") {
+		t.Errorf("go.mod not rewritten:\n%s", mod)
+	}
+	want := "# github.com/new/lib v1.2.0\n## explicit; go 1.21\ngithub.com/new/lib/sub\n# github.com/old/library v0.1.0\n## explicit\ngithub.com/old/library\n"
+	if got := readTree(t, root, "vendor/modules.txt"); got != want {
+		t.Errorf("modules.txt:\n%s", got)
+	}
+}
@@ -0,0 +1,146 @@
+package recipe
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// GitInfo 项目的git元数据，不是git仓库时为空
+type GitInfo struct {
+	Branch string
+	Commit string
+	Tag    string //HEAD上的标签
+	Dirty  bool   //存在未提交的修改
+}
+
+// LoadGitInfo 读取dir所在git仓库的元数据
+func LoadGitInfo(dir string) GitInfo {
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	info := GitInfo{
+		Commit: git("rev-parse", "HEAD"),
+	}
+	if info.Commit == "" {
+		return info
+	}
+	info.Branch = git("rev-parse", "--abbrev-ref", "HEAD")
+	info.Tag = git("describe", "--tags", "--exact-match")
+	info.Dirty = git("status", "--porcelain") != ""
+	return info
+}
+
+// CondContext CEL条件表达式的求值上下文
+type CondContext struct {
+	Platform string
+	Arch     string
+	Recipe   string
+	Brand    string
+	Variant  string
+	Env      map[string]string
+	Git      GitInfo
+}
+
+// NewCondContext 使用当前环境变量与projectPath的git元数据创建上下文
+func NewCondContext(recipeName, projectPath string) CondContext {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return CondContext{
+		Recipe: recipeName,
+		Env:    env,
+		Git:    LoadGitInfo(projectPath),
+	}
+}
+
+func (ctx CondContext) activation() map[string]any {
+	shortCommit := ctx.Git.Commit
+	if len(shortCommit) > 7 {
+		shortCommit = shortCommit[:7]
+	}
+	return map[string]any{
+		"platform": ctx.Platform,
+		"arch":     ctx.Arch,
+		"recipe":   ctx.Recipe,
+		"brand":    ctx.Brand,
+		"variant":  ctx.Variant,
+		"env":      ctx.Env,
+		"git": map[string]any{
+			"branch":       ctx.Git.Branch,
+			"commit":       ctx.Git.Commit,
+			"short_commit": shortCommit,
+			"tag":          ctx.Git.Tag,
+			"dirty":        ctx.Git.Dirty,
+		},
+	}
+}
+
+// Condition 一条when条件在某个编译目标上的求值结果
+type Condition struct {
+	Where   string //条件所在的选项块，如"linux.all_arch.hooks"
+	Expr    string
+	Matched bool
+}
+
+// Evaluator 编译并缓存CEL条件表达式
+type Evaluator struct {
+	env      *cel.Env
+	programs map[string]cel.Program
+}
+
+// Eval 在上下文中对表达式求值，表达式的结果必须是bool
+func (e *Evaluator) Eval(expr string, ctx CondContext) (bool, error) {
+	prg, ok := e.programs[expr]
+	if !ok {
+		ast, iss := e.env.Compile(expr)
+		if iss.Err() != nil {
+			return false, fmt.Errorf("when %q: %w", expr, iss.Err())
+		}
+		var err error
+		if prg, err = e.env.Program(ast); err != nil {
+			return false, fmt.Errorf("when %q: %w", expr, err)
+		}
+		e.programs[expr] = prg
+	}
+	out, _, err := prg.Eval(ctx.activation())
+	if err != nil {
+		return false, fmt.Errorf("when %q: %w", expr, err)
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("when %q: result must be bool", expr)
+	}
+	return matched, nil
+}
+
+func NewEvaluator() (*Evaluator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("platform", cel.StringType),
+		cel.Variable("arch", cel.StringType),
+		cel.Variable("recipe", cel.StringType),
+		cel.Variable("brand", cel.StringType),
+		cel.Variable("variant", cel.StringType),
+		cel.Variable("env", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("git", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluator{
+		env:      env,
+		programs: map[string]cel.Program{},
+	}, nil
+}
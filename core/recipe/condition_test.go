@@ -0,0 +1,137 @@
+package recipe
+
+import (
+	"testing"
+
+	"github.com/B9O2/bake/core/recipe/options"
+)
+
+func TestToConfigWhen(t *testing.T) {
+	r := Recipe{
+		Entrance: "./",
+		Pairs:    []string{"linux/amd64", "windows/386", "windows/amd64"},
+		AllPlatform: ArchOption{
+			"all_arch": options.Options{
+				Output: options.OptionOutput{
+					Zip: options.OptionZIP{Source: "a", Dest: "a.zip", When: "platform == 'windows' && arch != '386'"},
+				},
+				Hooks: options.OptionHooks{PreBuild: []string{"echo ci"}, When: "'CI' in env"},
+			},
+		},
+		Linux: ArchOption{
+			"all_arch": options.Options{
+				When:    "git.branch == 'release'",
+				Builder: options.OptionBuilder{Env: map[string]string{"RELEASE": "1"}},
+			},
+		},
+	}
+	ctx := CondContext{Recipe: "default", Env: map[string]string{"CI": "true"}, Git: GitInfo{Branch: "main"}}
+	cfg, err := r.ToConfig(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipped := map[string]bool{}
+	for _, pair := range cfg.Targets {
+		zipped[pair.Tag()] = !pair.Output.Zip.IsEmpty()
+		if len(pair.Hooks.PreBuild) != 1 {
+			t.Errorf("%s: hooks not applied", pair.Tag())
+		}
+		if pair.Recipe != "default" {
+			t.Errorf("%s: recipe %q", pair.Tag(), pair.Recipe)
+		}
+		if _, ok := pair.Builder.Env["RELEASE"]; ok {
+			t.Errorf("%s: linux block applied on branch main", pair.Tag())
+		}
+	}
+	want := map[string]bool{"linux_amd64": false, "windows_386": false, "windows_amd64": true}
+	for tag, w := range want {
+		if zipped[tag] != w {
+			t.Errorf("%s: zip %v, want %v", tag, zipped[tag], w)
+		}
+	}
+	if n := len(cfg.Targets[0].Conditions); n != 3 {
+		t.Errorf("linux_amd64: %d conditions recorded, want 3", n)
+	}
+
+	r.AllPlatform["all_arch"] = options.Options{When: "platform"}
+	if _, err = r.ToConfig(ctx, nil); err == nil {
+		t.Error("non-bool condition accepted")
+	}
+}
+
+func TestToConfigBrand(t *testing.T) {
+	r := Recipe{
+		Entrance: "./",
+		Pairs:    []string{"linux/amd64", "windows/amd64"},
+	}
+	brand := &Brand{Options: options.Options{
+		Output: options.OptionOutput{Name: "acme-{{.Platform}}-{{.Arch}}"},
+		Hooks:  options.OptionHooks{PreBuild: []string{"echo acme"}, When: "platform == 'linux'"},
+	}}
+	cfg, err := r.ToConfig(CondContext{Recipe: "default", Brand: "acme"}, brand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range cfg.Targets {
+		if pair.Brand != "acme" {
+			t.Errorf("%s: brand %q", pair.Tag(), pair.Brand)
+		}
+		want := "acme-" + pair.Platform + "-" + pair.Arch
+		if pair.Platform == "windows" {
+			want += ".exe"
+		}
+		if pair.Name() != want {
+			t.Errorf("%s: name %q, want %q", pair.Tag(), pair.Name(), want)
+		}
+		if l := pair.Layers; len(l) == 0 || l[len(l)-1] != "brand.acme" {
+			t.Errorf("%s: brand layer not last in %v", pair.Tag(), l)
+		}
+		if hooked := len(pair.Hooks.PreBuild) == 1; hooked != (pair.Platform == "linux") {
+			t.Errorf("%s: brand hooks applied %v", pair.Tag(), hooked)
+		}
+	}
+}
+
+func TestToConfigVariant(t *testing.T) {
+	r := Recipe{
+		Entrance: "./",
+		Pairs:    []string{"linux/arm/v6", "linux/arm/v7", "linux/amd64/v3", "linux/amd64"},
+		Linux: ArchOption{
+			"arm_v7": options.Options{Builder: options.OptionBuilder{Tags: []string{"neon"}}},
+		},
+	}
+	cfg, err := r.ToConfig(CondContext{Recipe: "default"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"linux_arm_v6": "GOARM=6", "linux_arm_v7": "GOARM=7", "linux_amd64_v3": "GOAMD64=v3", "linux_amd64": ""}
+	if len(cfg.Targets) != len(want) {
+		t.Fatalf("%d pairs, want %d", len(cfg.Targets), len(want))
+	}
+	for _, pair := range cfg.Targets {
+		env, err := pair.BuildEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, k := range []string{"GOARM", "GOAMD64"} {
+			if v, ok := env[k]; ok {
+				got = k + "=" + v
+			}
+		}
+		if w, ok := want[pair.Tag()]; !ok || got != w {
+			t.Errorf("%s: env %q, want %q", pair.Tag(), got, w)
+		}
+		if pair.Name() != pair.Tag() {
+			t.Errorf("%s: name %s", pair.Tag(), pair.Name())
+		}
+		if neon := len(pair.Builder.Tags) > 0; neon != (pair.Tag() == "linux_arm_v7") {
+			t.Errorf("%s: arm_v7 layer applied %v", pair.Tag(), neon)
+		}
+	}
+
+	r.Pairs = []string{"linux/arm/v9"}
+	if _, err = r.ToConfig(CondContext{}, nil); err == nil {
+		t.Error("unknown arm variant accepted")
+	}
+}
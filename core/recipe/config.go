@@ -1,9 +1,13 @@
 package recipe
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
+	"text/template"
 
 	"github.com/B9O2/bake/core/recipe/options"
 	"github.com/B9O2/bake/core/targets"
@@ -13,19 +17,59 @@ import (
 )
 
 type BuildPair struct {
-	Platform string
-	Arch     string
-	Rule     options.ReplaceRule
-	Remote   targets.Target
+	Recipe     string
+	Brand      string
+	Platform   string
+	Arch       string
+	Variant    string //架构变体，例如linux/arm/v7中的v7
+	Rule       options.ReplaceRule
+	Remote     targets.Target
+	Conditions []Condition //求值过的when条件
+	Layers     []string    //依次应用的选项层，如"all_platform.all_arch"、"brand.acme"
 
-	Builder options.OptionBuilder
-	Output  options.OptionOutput
+	Builder  options.OptionBuilder
+	Output   options.OptionOutput
+	Hooks    options.OptionHooks
+	Mod      options.OptionMod
+	Generate options.OptionGenerate
+	Inject   options.OptionInject
+	Verify   options.OptionVerify
 }
 
 func (bp BuildPair) Tag() string {
+	if bp.Variant != "" {
+		return fmt.Sprintf("%s_%s_%s", bp.Platform, bp.Arch, bp.Variant)
+	}
 	return fmt.Sprintf("%s_%s", bp.Platform, bp.Arch)
 }
 
+// Pair 配方中pairs的写法，例如"linux/arm/v7"
+func (bp BuildPair) Pair() string {
+	if bp.Variant != "" {
+		return bp.Platform + "/" + bp.Arch + "/" + bp.Variant
+	}
+	return bp.Platform + "/" + bp.Arch
+}
+
+// renderName 渲染output.name模板
+func (bp BuildPair) renderName(name string) (string, error) {
+	t, err := template.New("output.name").Option("missingkey=error").Parse(name)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, map[string]string{
+		"Recipe":   bp.Recipe,
+		"Brand":    bp.Brand,
+		"Platform": bp.Platform,
+		"Arch":     bp.Arch,
+		"Variant":  bp.Variant,
+		"Tag":      bp.Tag(),
+	})
+	return buf.String(), err
+}
+
+// Name 产物文件名，按平台与编译模式补全扩展名
 func (bp BuildPair) Name() string {
 	name := ""
 	if bp.Output.Path != "" {
@@ -33,41 +77,202 @@ func (bp BuildPair) Name() string {
 	} else {
 		name = bp.Tag()
 	}
-	if bp.Platform == "windows" && filepath.Ext(name) != ".exe" {
-		name += ".exe"
+	if ext := bp.Builder.OutputExt(bp.Platform, bp.Arch); ext != "" && filepath.Ext(name) != ext {
+		name += ext
 	}
 	return name
 }
 
+// WasmExec js/wasm产物需要Go工具链中的wasm_exec.js才能在浏览器或node中运行
+func (bp BuildPair) WasmExec() bool {
+	kind := bp.Builder.BuilderKind()
+	return bp.Platform == "js" && bp.Arch == "wasm" && (kind == options.BuilderKindGo || kind == options.BuilderKindGarble)
+}
+
+// OutputFiles 编译产生的所有文件，第一个为产物本身，其余为C头文件与wasm_exec.js等附带文件
+func (bp BuildPair) OutputFiles() []string {
+	name := bp.Name()
+	files := []string{name}
+	if bp.Builder.HasHeader() {
+		files = append(files, strings.TrimSuffix(name, filepath.Ext(name))+".h")
+	}
+	if bp.WasmExec() {
+		files = append(files, filepath.Join(filepath.Dir(name), "wasm_exec.js"))
+	}
+	return files
+}
+
+// LocalTarget 本地编译目标，builder.clean_env开启时只继承允许的环境变量
+func (bp BuildPair) LocalTarget() *targets.LocalTarget {
+	lt := targets.NewLocalTarget(bp.Platform, bp.Arch)
+	if bp.Builder.CleanEnv != nil && *bp.Builder.CleanEnv {
+		lt.SetCleanEnv(bp.Builder.PassEnv)
+	}
+	return lt
+}
+
+// BuildEnv 编译时的环境变量，builder.env优先于CGO、工具链与架构变体生成的变量
+func (bp BuildPair) BuildEnv() (map[string]string, error) {
+	env, err := bp.Builder.CGOEnv(bp.Platform, bp.Arch)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range bp.Builder.ToolchainEnv() {
+		env[k] = v
+	}
+	if bp.Variant != "" {
+		name, value, err := VariantEnv(bp.Arch, bp.Variant)
+		if err != nil {
+			return nil, err
+		}
+		env[name] = value
+	}
+	for k, v := range bp.Builder.Env {
+		env[k] = v
+	}
+	return env, nil
+}
+
+// BuildCommand 生成编译命令与环境变量，out为影子项目中的输出路径
+func (bp BuildPair) BuildCommand(out, entrance string) (string, []string, map[string]string, error) {
+	cmd, args, kindEnv, err := bp.Builder.Command(out, entrance, bp.Platform, bp.Arch)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	env, err := bp.BuildEnv()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	for k, v := range kindEnv {
+		if _, ok := env[k]; !ok {
+			env[k] = v
+		}
+	}
+	return cmd, args, env, nil
+}
+
+// ShadowKey 影子项目的特征，特征相同的编译目标可以共用同一份影子项目
+func (bp BuildPair) ShadowKey() string {
+	key := struct {
+		Builder    string
+		Dependency map[string]string
+		Module     map[string]string
+		Mod        options.OptionMod
+		Generate   options.OptionGenerate
+		Inject     options.OptionInject
+		Text       map[string]string
+		Steps      []options.ReplaceStep
+		Pair       string //模板替换与代码生成的结果与编译目标有关，不能共用影子项目
+		DirRules   []string
+		FileRules  []string
+	}{
+		Builder:    bp.Builder.GoPath(),
+		Dependency: bp.Rule.DependencyReplace,
+		Module:     bp.Rule.ModuleReplace,
+		Mod:        bp.Mod,
+		Generate:   bp.Generate,
+		Inject:     bp.Inject,
+		Text:       bp.Rule.ReplacementWords,
+		Steps:      bp.Rule.Steps,
+	}
+	perPair := !bp.Generate.IsEmpty()
+	for _, step := range bp.Rule.Steps {
+		if step.Kind == options.ReplaceTemplate {
+			perPair = true
+			break
+		}
+	}
+	if perPair {
+		key.Pair = bp.Recipe + "/" + bp.Tag() + "/" + bp.Name()
+	}
+	if bp.Rule.Range != nil {
+		key.DirRules = bp.Rule.Range.DirRules
+		for _, re := range bp.Rule.Range.FileNameRegexps {
+			key.FileRules = append(key.FileRules, re.String())
+		}
+	}
+	data, _ := json.Marshal(key)
+	return string(data)
+}
+
 type Config struct {
 	Debug            bool
+	Brand            string
 	Targets          []BuildPair
 	Entrance, Output string
+	Cache            options.OptionCache
+	Copy             options.OptionCopy
+	Checks           []string //构建前在影子项目中执行的检查
 }
 
-func LoadAllRecipes(filePath string) (map[string]Recipe, error) {
+// Groups 按影子项目特征对编译目标分组，保持编译目标的原有顺序
+func (cfg Config) Groups() [][]BuildPair {
+	var groups [][]BuildPair
+	index := map[string]int{}
+	for _, pair := range cfg.Targets {
+		key := pair.ShadowKey()
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], pair)
+		} else {
+			index[key] = len(groups)
+			groups = append(groups, []BuildPair{pair})
+		}
+	}
+	return groups
+}
+
+// LoadRecipeDoc 读取完整的配置文件
+func LoadRecipeDoc(filePath string) (RecipeDoc, error) {
 	doc := RecipeDoc{}
 	yes, err := utils.FileExists(filePath)
 	if !yes {
-		return map[string]Recipe{}, errors.New("Not a bake project, try 'bake init'")
+		return doc, errors.New("Not a bake project, try 'bake init'")
 	}
 	if err != nil {
-		return map[string]Recipe{}, err
+		return doc, err
 	}
 	if _, err := toml.DecodeFile(filePath, &doc); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+func LoadAllRecipes(filePath string) (map[string]Recipe, error) {
+	doc, err := LoadRecipeDoc(filePath)
+	if err != nil {
 		return map[string]Recipe{}, err
 	}
 	return doc.Recipes, nil
 }
 
-func LoadConfig(filePath, recipeName string) (Config, error) {
-	if recipes, err := LoadAllRecipes(filePath); err != nil {
+// LoadConfig 读取配方并生成编译配置，brand为空时使用配方中设置的品牌
+func LoadConfig(filePath, recipeName, brand string) (Config, error) {
+	if doc, err := LoadRecipeDoc(filePath); err != nil {
 		return Config{}, err
 	} else {
-		cfg, err := recipes[recipeName].ToConfig()
+		r := doc.Recipes[recipeName]
+		ctx := NewCondContext(recipeName, filepath.Dir(filePath))
+		if brand == "" {
+			brand = r.Brand
+		}
+		var b *Brand
+		if brand != "" {
+			found, ok := doc.Brands[brand]
+			if !ok {
+				return Config{}, fmt.Errorf("brand '%s' not found", brand)
+			}
+			b, ctx.Brand = &found, brand
+		}
+		cfg, err := r.ToConfig(ctx, b)
 		if err != nil {
 			return Config{}, err
 		}
+		cfg.Cache = doc.Cache
+		for _, pair := range cfg.Targets {
+			if err = pair.Inject.Validate(filepath.Dir(filePath)); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", pair.Tag(), err)
+			}
+		}
 		return cfg, nil
 	}
 }
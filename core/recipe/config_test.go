@@ -2,12 +2,14 @@ package recipe
 
 import (
 	"fmt"
+	"github.com/B9O2/bake/core/recipe/options"
 	"github.com/BurntSushi/toml"
+	"strings"
 	"testing"
 )
 
 func TestLoadConfig(t *testing.T) {
-	config, err := LoadConfig("./RECIPE.toml", "default")
+	config, err := LoadConfig("./RECIPE.toml", "default", "")
 	if err != nil {
 		fmt.Println(err)
 	} else {
@@ -39,3 +41,49 @@ func TestLoadRecipeDoc(t *testing.T) {
 
 	}
 }
+
+func TestGroupsGenerate(t *testing.T) {
+	r := Recipe{
+		Entrance: "./",
+		Pairs:    []string{"linux/amd64", "linux/arm64", "windows/amd64", "windows/arm64"},
+		Windows: ArchOption{
+			"all_arch": options.Options{Generate: options.OptionGenerate{Run: "stringer"}},
+		},
+	}
+	cfg, err := r.ToConfig(CondContext{Recipe: "default"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//linux共用一份影子项目，设置了generate的windows编译目标各自一份
+	groups := cfg.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("%d groups, want 3", len(groups))
+	}
+	for _, group := range groups {
+		generate := !group[0].Generate.IsEmpty()
+		if generate != (group[0].Platform == "windows") || (generate && len(group) != 1) || (!generate && len(group) != 2) {
+			t.Errorf("group %s: %d pairs, generate %v", group[0].Tag(), len(group), generate)
+		}
+	}
+}
+
+func TestLocalTargetCleanEnv(t *testing.T) {
+	clean := true
+	r := Recipe{
+		Entrance: "./",
+		Pairs:    []string{"linux/amd64", "linux/arm64"},
+		Linux: ArchOption{
+			"amd64": options.Options{Builder: options.OptionBuilder{CleanEnv: &clean, PassEnv: []string{"GOPROXY"}}},
+		},
+	}
+	cfg, err := r.ToConfig(CondContext{Recipe: "default"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range cfg.Targets {
+		info := pair.Remote.Info()
+		if isClean := strings.Contains(info, "clean env") && strings.Contains(info, "GOPROXY"); isClean != (pair.Arch == "amd64") {
+			t.Errorf("%s: %s", pair.Tag(), info)
+		}
+	}
+}
@@ -1,24 +1,172 @@
 package options
 
+import (
+	"slices"
+	"strings"
+)
+
 type OptionBuilder struct {
+	Kind string            `toml:"kind"` //go、garble、tinygo或custom
 	Path string            `toml:"path"`
-	Args []string          `toml:"args"`
+	Args []string          `toml:"args"` //原始编译参数，追加在结构化参数之后，设置后不再使用默认的trimpath与strip
 	Env  map[string]string `toml:"env"`
+
+	//本地编译的环境隔离，开启后只从bake进程传递PATH、HOME、GOPATH、GOCACHE与pass_env中的变量
+	CleanEnv *bool    `toml:"clean_env"`
+	PassEnv  []string `toml:"pass_env"`
+
+	//结构化编译参数，tags取并集，ldflags、gcflags与asmflags依次拼接，其余后设置的优先
+	Tags      []string `toml:"tags"`
+	LDFlags   string   `toml:"ldflags"`
+	GCFlags   string   `toml:"gcflags"`
+	ASMFlags  string   `toml:"asmflags"`
+	Trimpath  *bool    `toml:"trimpath"` //go与garble默认开启
+	Strip     *bool    `toml:"strip"`    //在ldflags前加入"-w -s"，go与garble默认开启
+	Race      *bool    `toml:"race"`
+	BuildMode string   `toml:"buildmode"`
+	PGO       string   `toml:"pgo"`
+	Mod       string   `toml:"mod"` //-mod，例如"vendor"或"mod"
+
+	ToolFlags []string `toml:"tool_flags"` //编译器自身的参数，例如garble的"-literals"
+	Target    string   `toml:"target"`     //tinygo的-target
+	Template  string   `toml:"template"`   //custom类型的命令模板
+
+	//Go工具链
+	GoVersion      string `toml:"go_version"`      //例如"1.22.5"，通过GOTOOLCHAIN在编译目标上使用该版本
+	ToolchainProxy string `toml:"toolchain_proxy"` //下载工具链使用的GOPROXY，可以是本地镜像"file:///path"
+
+	//CGO
+	CGO        *bool  `toml:"cgo"`
+	CC         string `toml:"cc"` //设置为"zig"时使用内置的zig cc交叉编译
+	CXX        string `toml:"cxx"`
+	CGOCFlags  string `toml:"cgo_cflags"`
+	CGOLDFlags string `toml:"cgo_ldflags"`
+	Sysroot    string `toml:"sysroot"` //编译目标上的sysroot路径
 }
 
 func (ob *OptionBuilder) Patch(patchOpt OptionBuilder) OptionBuilder {
+	if patchOpt.Kind != "" {
+		ob.Kind = patchOpt.Kind
+	}
 	if patchOpt.Path != "" {
 		ob.Path = patchOpt.Path
 	}
+	if len(patchOpt.ToolFlags) > 0 {
+		ob.ToolFlags = patchOpt.ToolFlags
+	}
+	if patchOpt.Target != "" {
+		ob.Target = patchOpt.Target
+	}
+	if patchOpt.Template != "" {
+		ob.Template = patchOpt.Template
+	}
 	if len(patchOpt.Args) > 0 {
 		ob.Args = patchOpt.Args
 	}
 
+	for _, tag := range patchOpt.Tags {
+		if !slices.Contains(ob.Tags, tag) {
+			ob.Tags = append(ob.Tags, tag)
+		}
+	}
+	ob.LDFlags = joinFlags(ob.LDFlags, patchOpt.LDFlags)
+	ob.GCFlags = joinFlags(ob.GCFlags, patchOpt.GCFlags)
+	ob.ASMFlags = joinFlags(ob.ASMFlags, patchOpt.ASMFlags)
+	if patchOpt.Trimpath != nil {
+		ob.Trimpath = patchOpt.Trimpath
+	}
+	if patchOpt.Strip != nil {
+		ob.Strip = patchOpt.Strip
+	}
+	if patchOpt.Race != nil {
+		ob.Race = patchOpt.Race
+	}
+	if patchOpt.BuildMode != "" {
+		ob.BuildMode = patchOpt.BuildMode
+	}
+	if patchOpt.PGO != "" {
+		ob.PGO = patchOpt.PGO
+	}
+	if patchOpt.Mod != "" {
+		ob.Mod = patchOpt.Mod
+	}
+
 	if ob.Env == nil {
 		ob.Env = map[string]string{}
 	}
 	for k, v := range patchOpt.Env {
 		ob.Env[k] = v
 	}
+	if patchOpt.CleanEnv != nil {
+		ob.CleanEnv = patchOpt.CleanEnv
+	}
+	for _, name := range patchOpt.PassEnv {
+		if !slices.Contains(ob.PassEnv, name) {
+			ob.PassEnv = append(ob.PassEnv, name)
+		}
+	}
+
+	if patchOpt.GoVersion != "" {
+		ob.GoVersion = patchOpt.GoVersion
+	}
+	if patchOpt.ToolchainProxy != "" {
+		ob.ToolchainProxy = patchOpt.ToolchainProxy
+	}
+
+	if patchOpt.CGO != nil {
+		ob.CGO = patchOpt.CGO
+	}
+	if patchOpt.CC != "" {
+		ob.CC = patchOpt.CC
+	}
+	if patchOpt.CXX != "" {
+		ob.CXX = patchOpt.CXX
+	}
+	if patchOpt.CGOCFlags != "" {
+		ob.CGOCFlags = patchOpt.CGOCFlags
+	}
+	if patchOpt.CGOLDFlags != "" {
+		ob.CGOLDFlags = patchOpt.CGOLDFlags
+	}
+	if patchOpt.Sysroot != "" {
+		ob.Sysroot = patchOpt.Sysroot
+	}
 	return *ob
 }
+
+// Toolchain 固定的工具链名称，例如"go1.22.5"，未设置时为空
+func (ob *OptionBuilder) Toolchain() string {
+	if ob.GoVersion == "" {
+		return ""
+	}
+	return "go" + strings.TrimPrefix(ob.GoVersion, "go")
+}
+
+// ToolchainEnv 固定工具链所需的环境变量
+func (ob *OptionBuilder) ToolchainEnv() map[string]string {
+	env := map[string]string{}
+	if toolchain := ob.Toolchain(); toolchain != "" {
+		env["GOTOOLCHAIN"] = toolchain
+		if ob.ToolchainProxy != "" {
+			env["GOPROXY"] = ob.ToolchainProxy
+		}
+	}
+	return env
+}
+
+// CGOEnabled 未设置cgo时由编译模式决定
+func (ob *OptionBuilder) CGOEnabled() bool {
+	if ob.CGO == nil {
+		return ob.NeedsCGO()
+	}
+	return *ob.CGO
+}
+
+// joinFlags 以空格拼接两层的参数
+func joinFlags(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + " " + b
+}
@@ -0,0 +1,82 @@
+package options
+
+import "fmt"
+
+// 编译模式，对应go build -buildmode
+const (
+	BuildModeDefault  = "default"
+	BuildModeExe      = "exe"
+	BuildModePIE      = "pie"
+	BuildModeCShared  = "c-shared"
+	BuildModeCArchive = "c-archive"
+	BuildModePlugin   = "plugin"
+)
+
+// 支持plugin的平台
+var pluginPlatforms = map[string]bool{"linux": true, "darwin": true, "freebsd": true}
+
+// BuildModeKind 编译模式，未设置时为default
+func (ob *OptionBuilder) BuildModeKind() string {
+	if ob.BuildMode == "" {
+		return BuildModeDefault
+	}
+	return ob.BuildMode
+}
+
+// ValidateBuildMode 检查编译模式是否受支持，不产生单个产物的archive与shared模式不受支持
+func (ob *OptionBuilder) ValidateBuildMode(platform, arch string) error {
+	switch mode := ob.BuildModeKind(); mode {
+	case BuildModeDefault, BuildModeExe, BuildModePIE, BuildModeCShared, BuildModeCArchive:
+		if arch == "wasm" && mode != BuildModeDefault && mode != BuildModeExe {
+			return fmt.Errorf("buildmode %s is not supported on %s/%s", mode, platform, arch)
+		}
+		return nil
+	case BuildModePlugin:
+		if !pluginPlatforms[platform] {
+			return fmt.Errorf("buildmode plugin is not supported on %s", platform)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported buildmode '%s'", mode)
+	}
+}
+
+// NeedsCGO c-shared、c-archive与plugin必须启用CGO
+func (ob *OptionBuilder) NeedsCGO() bool {
+	switch ob.BuildModeKind() {
+	case BuildModeCShared, BuildModeCArchive, BuildModePlugin:
+		return true
+	}
+	return false
+}
+
+// OutputExt 产物的扩展名
+func (ob *OptionBuilder) OutputExt(platform, arch string) string {
+	switch ob.BuildModeKind() {
+	case BuildModeCShared:
+		switch platform {
+		case "windows":
+			return ".dll"
+		case "darwin", "ios":
+			return ".dylib"
+		}
+		return ".so"
+	case BuildModeCArchive:
+		return ".a"
+	case BuildModePlugin:
+		return ".so"
+	}
+	switch {
+	case arch == "wasm":
+		return ".wasm"
+	case platform == "windows":
+		return ".exe"
+	}
+	return ""
+}
+
+// HasHeader c-shared与c-archive会生成与产物同名的C头文件
+func (ob *OptionBuilder) HasHeader() bool {
+	mode := ob.BuildModeKind()
+	return mode == BuildModeCShared || mode == BuildModeCArchive
+}
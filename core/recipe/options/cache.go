@@ -0,0 +1,20 @@
+package options
+
+import "github.com/B9O2/bake/utils"
+
+// DefaultCacheMaxSize 默认缓存容量上限
+const DefaultCacheMaxSize = "2GB"
+
+// OptionCache 构建缓存选项
+type OptionCache struct {
+	Dir     string `toml:"dir"`      //缓存目录，默认 ~/.cache/bake
+	MaxSize string `toml:"max_size"` //容量上限，例如"2GB"，"0"表示不限制
+}
+
+// MaxSizeBytes 容量上限的字节数
+func (oc *OptionCache) MaxSizeBytes() (int64, error) {
+	if oc.MaxSize == "" {
+		return utils.ParseSize(DefaultCacheMaxSize)
+	}
+	return utils.ParseSize(oc.MaxSize)
+}
@@ -0,0 +1,82 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ZigCC 使用zig作为C工具链时的cc取值
+const ZigCC = "zig"
+
+// zigTargets GOOS/GOARCH到zig目标三元组的映射
+var zigTargets = map[string]string{
+	"linux/amd64":    "x86_64-linux-gnu",
+	"linux/386":      "x86-linux-gnu",
+	"linux/arm64":    "aarch64-linux-gnu",
+	"linux/arm":      "arm-linux-gnueabihf",
+	"linux/riscv64":  "riscv64-linux-gnu",
+	"linux/ppc64le":  "powerpc64le-linux-gnu",
+	"linux/s390x":    "s390x-linux-gnu",
+	"linux/mips":     "mips-linux-gnueabi",
+	"linux/mipsle":   "mipsel-linux-gnueabi",
+	"linux/mips64":   "mips64-linux-gnuabi64",
+	"linux/mips64le": "mips64el-linux-gnuabi64",
+	"linux/loong64":  "loongarch64-linux-gnu",
+	"windows/amd64":  "x86_64-windows-gnu",
+	"windows/386":    "x86-windows-gnu",
+	"windows/arm64":  "aarch64-windows-gnu",
+	"darwin/amd64":   "x86_64-macos",
+	"darwin/arm64":   "aarch64-macos",
+	"freebsd/amd64":  "x86_64-freebsd",
+	"freebsd/386":    "x86-freebsd",
+	"freebsd/arm64":  "aarch64-freebsd",
+}
+
+// ZigTarget 返回平台架构对应的zig目标三元组
+func ZigTarget(platform, arch string) (string, error) {
+	if triple, ok := zigTargets[platform+"/"+arch]; ok {
+		return triple, nil
+	}
+	return "", fmt.Errorf("no zig target for '%s/%s'", platform, arch)
+}
+
+// CGOEnv 生成CGO相关的环境变量，未启用CGO时返回空
+func (ob *OptionBuilder) CGOEnv(platform, arch string) (map[string]string, error) {
+	env := map[string]string{}
+	if !ob.CGOEnabled() {
+		return env, nil
+	}
+	env["CGO_ENABLED"] = "1"
+
+	cc, cxx := ob.CC, ob.CXX
+	if cc == ZigCC {
+		triple, err := ZigTarget(platform, arch)
+		if err != nil {
+			return nil, err
+		}
+		cc = "zig cc -target " + triple
+		if cxx == "" || cxx == ZigCC {
+			cxx = "zig c++ -target " + triple
+		}
+	}
+	if cc != "" {
+		env["CC"] = cc
+	}
+	if cxx != "" {
+		env["CXX"] = cxx
+	}
+
+	cflags, ldflags := ob.CGOCFlags, ob.CGOLDFlags
+	if ob.Sysroot != "" {
+		sysroot := "--sysroot=" + ob.Sysroot
+		cflags = strings.TrimSpace(cflags + " " + sysroot)
+		ldflags = strings.TrimSpace(ldflags + " " + sysroot)
+	}
+	if cflags != "" {
+		env["CGO_CFLAGS"] = cflags
+	}
+	if ldflags != "" {
+		env["CGO_LDFLAGS"] = ldflags
+	}
+	return env, nil
+}
@@ -0,0 +1,8 @@
+package options
+
+// OptionCopy 复制影子项目时的文件筛选选项，规则使用gitignore语法
+type OptionCopy struct {
+	Include   []string `toml:"include"`   //重新包含被忽略的文件
+	Exclude   []string `toml:"exclude"`   //额外忽略的文件
+	GitIgnore bool     `toml:"gitignore"` //同时遵循项目中的.gitignore
+}
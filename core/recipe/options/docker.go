@@ -6,6 +6,7 @@ type OptionDocker struct {
 	Container string `toml:"container"`
 	Image     string `toml:"image"`
 	Temp      string `toml:"temp"`
+	GoCache   string `toml:"gocache"` //持久化Go缓存的卷名称，"off"关闭
 }
 
 func (od *OptionDocker) Patch(patchOpt OptionDocker) OptionDocker {
@@ -21,5 +22,8 @@ func (od *OptionDocker) Patch(patchOpt OptionDocker) OptionDocker {
 	if patchOpt.Temp != "" {
 		od.Temp = patchOpt.Temp
 	}
+	if patchOpt.GoCache != "" {
+		od.GoCache = patchOpt.GoCache
+	}
 	return *od
 }
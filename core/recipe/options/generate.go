@@ -0,0 +1,42 @@
+package options
+
+// OptionGenerate 编译前在影子项目中执行的代码生成
+type OptionGenerate struct {
+	Packages []string `toml:"packages"` //执行go generate的包，只设置run时为["./..."]
+	Run      string   `toml:"run"`      //go generate -run
+	Commands []string `toml:"commands"` //go generate之后执行的生成命令
+}
+
+func (og *OptionGenerate) Patch(patchOpt OptionGenerate) OptionGenerate {
+	if len(patchOpt.Packages) > 0 {
+		og.Packages = patchOpt.Packages
+	}
+	if patchOpt.Run != "" {
+		og.Run = patchOpt.Run
+	}
+	if len(patchOpt.Commands) > 0 {
+		og.Commands = patchOpt.Commands
+	}
+	return *og
+}
+
+// IsEmpty 没有需要执行的生成
+func (og *OptionGenerate) IsEmpty() bool {
+	return len(og.Packages)+len(og.Commands) == 0 && og.Run == ""
+}
+
+// GenerateArgs go generate的参数，不需要执行go generate时返回nil
+func (og *OptionGenerate) GenerateArgs() []string {
+	packages := og.Packages
+	if len(packages) == 0 {
+		if og.Run == "" {
+			return nil
+		}
+		packages = []string{"./..."}
+	}
+	args := []string{"generate"}
+	if og.Run != "" {
+		args = append(args, "-run", og.Run)
+	}
+	return append(args, packages...)
+}
@@ -0,0 +1,22 @@
+package options
+
+// OptionHooks 在编译目标上执行的钩子命令
+type OptionHooks struct {
+	When       string   `toml:"when"`
+	PreBuild   []string `toml:"pre_build"`   //编译前
+	PostBuild  []string `toml:"post_build"`  //编译后，复制产物前
+	PostOutput []string `toml:"post_output"` //ZIP压缩与SFTP上传后
+}
+
+func (oh *OptionHooks) Patch(patchOpt OptionHooks) OptionHooks {
+	if len(patchOpt.PreBuild) > 0 {
+		oh.PreBuild = patchOpt.PreBuild
+	}
+	if len(patchOpt.PostBuild) > 0 {
+		oh.PostBuild = patchOpt.PostBuild
+	}
+	if len(patchOpt.PostOutput) > 0 {
+		oh.PostOutput = patchOpt.PostOutput
+	}
+	return *oh
+}
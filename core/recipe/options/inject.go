@@ -0,0 +1,76 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OptionInject 复制到影子项目中的文件与目录，"影子项目中的路径" = "来源路径"，来源的相对路径以项目目录为基准
+type OptionInject struct {
+	Files map[string]string `toml:"files"`
+	Dirs  map[string]string `toml:"dirs"`
+}
+
+func (oi *OptionInject) Patch(patchOpt OptionInject) OptionInject {
+	if oi.Files == nil {
+		oi.Files = map[string]string{}
+	}
+	for k, v := range patchOpt.Files {
+		oi.Files[k] = v
+	}
+	if oi.Dirs == nil {
+		oi.Dirs = map[string]string{}
+	}
+	for k, v := range patchOpt.Dirs {
+		oi.Dirs[k] = v
+	}
+	return *oi
+}
+
+// IsEmpty 没有需要注入的文件
+func (oi *OptionInject) IsEmpty() bool {
+	return len(oi.Files)+len(oi.Dirs) == 0
+}
+
+// Source 来源的完整路径
+func (oi *OptionInject) Source(projectPath, src string) string {
+	if filepath.IsAbs(src) {
+		return src
+	}
+	return filepath.Join(projectPath, src)
+}
+
+// Validate 检查注入的目标路径不会跳出影子项目，并且来源存在
+func (oi *OptionInject) Validate(projectPath string) error {
+	check := func(kind, dest, src string, dir bool) error {
+		clean := filepath.Clean(filepath.FromSlash(dest))
+		if filepath.IsAbs(dest) || filepath.VolumeName(dest) != "" || clean == "." || clean == ".." ||
+			strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("inject.%s '%s': destination must stay inside the project", kind, dest)
+		}
+		info, err := os.Stat(oi.Source(projectPath, src))
+		if err != nil {
+			return fmt.Errorf("inject.%s '%s': %w", kind, dest, err)
+		}
+		if info.IsDir() != dir {
+			if dir {
+				return fmt.Errorf("inject.dirs '%s': source '%s' is not a directory", dest, src)
+			}
+			return fmt.Errorf("inject.files '%s': source '%s' is a directory", dest, src)
+		}
+		return nil
+	}
+	for _, dest := range sortedKeys(oi.Files) {
+		if err := check("files", dest, oi.Files[dest], false); err != nil {
+			return err
+		}
+	}
+	for _, dest := range sortedKeys(oi.Dirs) {
+		if err := check("dirs", dest, oi.Dirs[dest], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package options
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// 编译器类型
+const (
+	BuilderKindGo     = "go"
+	BuilderKindGarble = "garble"
+	BuilderKindTinyGo = "tinygo"
+	BuilderKindCustom = "custom"
+)
+
+// DefaultStripFlags strip开启时加在ldflags前的参数
+const DefaultStripFlags = "-w -s"
+
+// CommandTemplateData 自定义编译命令模板中可用的变量
+type CommandTemplateData struct {
+	Path     string
+	Flags    string //经过shell转义的编译参数
+	Out      string
+	Entrance string
+	Platform string
+	Arch     string
+}
+
+// BuilderKind 编译器类型，默认为go
+func (ob *OptionBuilder) BuilderKind() string {
+	if ob.Kind == "" {
+		return BuilderKindGo
+	}
+	return ob.Kind
+}
+
+// ExecPath 编译器路径，未设置时使用编译器类型的默认命令
+func (ob *OptionBuilder) ExecPath() string {
+	if ob.Path != "" {
+		return ob.Path
+	}
+	switch ob.BuilderKind() {
+	case BuilderKindGarble:
+		return "garble"
+	case BuilderKindTinyGo:
+		return "tinygo"
+	default:
+		return "go"
+	}
+}
+
+// GoPath vendor与查询版本时使用的go命令
+func (ob *OptionBuilder) GoPath() string {
+	if ob.BuilderKind() == BuilderKindGo {
+		return ob.ExecPath()
+	}
+	return "go"
+}
+
+// flagDefault 未设置的开关在go与garble且没有原始参数时默认开启
+func (ob *OptionBuilder) flagDefault(flag *bool) bool {
+	if flag != nil {
+		return *flag
+	}
+	kind := ob.BuilderKind()
+	return len(ob.Args) == 0 && (kind == BuilderKindGo || kind == BuilderKindGarble)
+}
+
+// BuildArgs 由结构化参数生成编译参数，原始参数追加在最后
+func (ob *OptionBuilder) BuildArgs() []string {
+	var args []string
+	if ob.flagDefault(ob.Trimpath) {
+		args = append(args, "-trimpath")
+	}
+	if ob.Race != nil && *ob.Race {
+		args = append(args, "-race")
+	}
+	if ob.BuildMode != "" {
+		args = append(args, "-buildmode="+ob.BuildMode)
+	}
+	if ob.Mod != "" {
+		args = append(args, "-mod="+ob.Mod)
+	}
+	if ob.PGO != "" {
+		args = append(args, "-pgo="+ob.PGO)
+	}
+	if len(ob.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(ob.Tags, ","))
+	}
+	ldflags := ob.LDFlags
+	if ob.flagDefault(ob.Strip) {
+		ldflags = joinFlags(DefaultStripFlags, ldflags)
+	}
+	if ldflags != "" {
+		args = append(args, "-ldflags", ldflags)
+	}
+	if ob.GCFlags != "" {
+		args = append(args, "-gcflags", ob.GCFlags)
+	}
+	if ob.ASMFlags != "" {
+		args = append(args, "-asmflags", ob.ASMFlags)
+	}
+	return append(args, ob.Args...)
+}
+
+// Command 生成完整的编译命令及编译器类型需要的环境变量
+func (ob *OptionBuilder) Command(out, entrance, platform, arch string) (string, []string, map[string]string, error) {
+	path := ob.ExecPath()
+	flags := ob.BuildArgs()
+	env := map[string]string{}
+	var args []string
+
+	switch ob.BuilderKind() {
+	case BuilderKindGo:
+		args = append(args, "build", "-buildvcs=false")
+		args = append(args, flags...)
+		args = append(args, "-o", out, entrance)
+	case BuilderKindGarble:
+		//garble [garble flags] build [go flags]
+		args = append(args, ob.ToolFlags...)
+		args = append(args, "build", "-buildvcs=false")
+		args = append(args, flags...)
+		args = append(args, "-o", out, entrance)
+		env["GOGARBLE"] = "*"
+	case BuilderKindTinyGo:
+		args = append(args, "build", "-o", out)
+		if ob.Target != "" {
+			args = append(args, "-target", ob.Target)
+		}
+		args = append(args, ob.ToolFlags...)
+		args = append(args, flags...)
+		args = append(args, entrance)
+	case BuilderKindCustom:
+		if ob.Template == "" {
+			return "", nil, nil, errors.New("builder.template is required for custom builder")
+		}
+		tmpl, err := template.New("builder").Parse(ob.Template)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		buf := &bytes.Buffer{}
+		err = tmpl.Execute(buf, CommandTemplateData{
+			Path:     path,
+			Flags:    shellquote.Join(flags...),
+			Out:      shellquote.Join(out),
+			Entrance: shellquote.Join(entrance),
+			Platform: platform,
+			Arch:     arch,
+		})
+		if err != nil {
+			return "", nil, nil, err
+		}
+		words, err := shellquote.Split(buf.String())
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if len(words) == 0 {
+			return "", nil, nil, errors.New("builder.template renders an empty command")
+		}
+		return words[0], words[1:], env, nil
+	default:
+		return "", nil, nil, fmt.Errorf("unknown builder kind '%s'", ob.Kind)
+	}
+	return path, args, env, nil
+}
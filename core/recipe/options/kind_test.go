@@ -0,0 +1,97 @@
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilderCommand(t *testing.T) {
+	yes := true
+	cases := []struct {
+		builder OptionBuilder
+		cmd     string
+		args    []string
+	}{
+		{
+			OptionBuilder{},
+			"go",
+			[]string{"build", "-buildvcs=false", "-trimpath", "-ldflags", "-w -s", "-o", "bin/app", "./"},
+		},
+		{
+			OptionBuilder{Kind: BuilderKindGarble, ToolFlags: []string{"-literals"}},
+			"garble",
+			[]string{"-literals", "build", "-buildvcs=false", "-trimpath", "-ldflags", "-w -s", "-o", "bin/app", "./"},
+		},
+		{
+			OptionBuilder{Tags: []string{"netgo"}, LDFlags: "-X main.v=1", BuildMode: "pie", Race: &yes, Mod: "vendor"},
+			"go",
+			[]string{"build", "-buildvcs=false", "-trimpath", "-race", "-buildmode=pie", "-mod=vendor", "-tags=netgo", "-ldflags", "-w -s -X main.v=1", "-o", "bin/app", "./"},
+		},
+		{
+			OptionBuilder{Args: []string{"-a"}, GCFlags: "all=-N -l"},
+			"go",
+			[]string{"build", "-buildvcs=false", "-gcflags", "all=-N -l", "-a", "-o", "bin/app", "./"},
+		},
+		{
+			OptionBuilder{Kind: BuilderKindTinyGo, Target: "wasi"},
+			"tinygo",
+			[]string{"build", "-o", "bin/app", "-target", "wasi", "./"},
+		},
+		{
+			OptionBuilder{Kind: BuilderKindCustom, Path: "gox", Args: []string{"-ldflags", "-X a=b c"}, Template: "{{.Path}} {{.Flags}} build -o {{.Out}} {{.Entrance}}"},
+			"gox",
+			[]string{"-ldflags", "-X a=b c", "build", "-o", "bin/app", "./"},
+		},
+	}
+	for _, c := range cases {
+		cmd, args, _, err := c.builder.Command("bin/app", "./", "linux", "amd64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cmd != c.cmd || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%s: got %s %q, want %s %q", c.builder.BuilderKind(), cmd, args, c.cmd, c.args)
+		}
+	}
+}
+
+func TestBuilderPatchFlags(t *testing.T) {
+	no := false
+	ob := OptionBuilder{}
+	ob.Patch(OptionBuilder{Tags: []string{"netgo", "osusergo"}, LDFlags: "-X main.a=1"})
+	ob.Patch(OptionBuilder{Tags: []string{"osusergo", "prod"}, LDFlags: "-X main.b=2", Trimpath: &no})
+	want := []string{"-tags=netgo,osusergo,prod", "-ldflags", "-w -s -X main.a=1 -X main.b=2"}
+	if args := ob.BuildArgs(); !reflect.DeepEqual(args, want) {
+		t.Errorf("got %q, want %q", args, want)
+	}
+}
+
+func TestBuildModeOutput(t *testing.T) {
+	cases := []struct {
+		mode, platform, arch, ext string
+	}{
+		{"", "windows", "amd64", ".exe"},
+		{"", "js", "wasm", ".wasm"},
+		{BuildModeCShared, "windows", "amd64", ".dll"},
+		{BuildModeCShared, "darwin", "arm64", ".dylib"},
+		{BuildModeCShared, "linux", "amd64", ".so"},
+		{BuildModeCArchive, "windows", "amd64", ".a"},
+		{BuildModePlugin, "linux", "amd64", ".so"},
+	}
+	for _, c := range cases {
+		ob := OptionBuilder{BuildMode: c.mode}
+		if ext := ob.OutputExt(c.platform, c.arch); ext != c.ext {
+			t.Errorf("%s %s/%s: ext %q, want %q", c.mode, c.platform, c.arch, ext, c.ext)
+		}
+		if err := ob.ValidateBuildMode(c.platform, c.arch); err != nil {
+			t.Error(err)
+		}
+	}
+	if !(&OptionBuilder{BuildMode: BuildModeCShared}).CGOEnabled() {
+		t.Error("c-shared without cgo")
+	}
+	for _, ob := range []OptionBuilder{{BuildMode: BuildModePlugin}, {BuildMode: "shared"}} {
+		if err := ob.ValidateBuildMode("windows", "amd64"); err == nil {
+			t.Errorf("buildmode %s accepted on windows", ob.BuildMode)
+		}
+	}
+}
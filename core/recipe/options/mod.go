@@ -0,0 +1,87 @@
+package options
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// OptionMod 在影子项目中对go.mod的修改，与go mod edit的-replace、-require、-exclude相同
+type OptionMod struct {
+	Replace map[string]string `toml:"replace"` //"旧模块[@版本]" = "新模块@版本"或本地路径
+	Require map[string]string `toml:"require"` //"模块" = "版本"
+	Exclude []string          `toml:"exclude"` //"模块@版本"，各层取并集
+}
+
+func (om *OptionMod) Patch(patchOpt OptionMod) OptionMod {
+	if om.Replace == nil {
+		om.Replace = map[string]string{}
+	}
+	for k, v := range patchOpt.Replace {
+		om.Replace[k] = v
+	}
+	if om.Require == nil {
+		om.Require = map[string]string{}
+	}
+	for k, v := range patchOpt.Require {
+		om.Require[k] = v
+	}
+	for _, exclude := range patchOpt.Exclude {
+		if !slices.Contains(om.Exclude, exclude) {
+			om.Exclude = append(om.Exclude, exclude)
+		}
+	}
+	return *om
+}
+
+// IsEmpty 没有任何修改
+func (om *OptionMod) IsEmpty() bool {
+	return len(om.Replace)+len(om.Require)+len(om.Exclude) == 0
+}
+
+// EditArgs go mod edit的参数，按模块排序。影子项目位于临时目录，相对路径的替换按projectPath转为绝对路径
+func (om *OptionMod) EditArgs(projectPath string) ([]string, error) {
+	args := []string{"mod", "edit"}
+	for _, old := range sortedKeys(om.Replace) {
+		dst := om.Replace[old]
+		if strings.HasPrefix(dst, "./") || strings.HasPrefix(dst, "../") {
+			dst = filepath.Join(projectPath, dst)
+		}
+		args = append(args, fmt.Sprintf("-replace=%s=%s", old, dst))
+	}
+	for _, path := range sortedKeys(om.Require) {
+		args = append(args, fmt.Sprintf("-require=%s@%s", path, om.Require[path]))
+	}
+	for _, exclude := range om.Exclude {
+		if !strings.Contains(exclude, "@") {
+			return nil, fmt.Errorf("mod.exclude '%s' needs a version, e.g. module@v1.2.3", exclude)
+		}
+		args = append(args, "-exclude="+exclude)
+	}
+	return args, nil
+}
+
+// DownloadArgs 下载被修改的模块并记录到go.sum的go mod download参数，没有需要下载的模块时返回nil
+func (om *OptionMod) DownloadArgs() []string {
+	var paths []string
+	for _, old := range sortedKeys(om.Replace) {
+		path, _, _ := strings.Cut(old, "@")
+		paths = append(paths, path)
+	}
+	paths = append(paths, sortedKeys(om.Require)...)
+	if len(paths) == 0 {
+		return nil
+	}
+	return append([]string{"mod", "download"}, paths...)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
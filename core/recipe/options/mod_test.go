@@ -0,0 +1,66 @@
+package options
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOptionMod(t *testing.T) {
+	project := filepath.FromSlash("/work/app")
+	cases := []struct {
+		layers   []OptionMod
+		edit     []string
+		download []string
+		err      bool
+	}{
+		{
+			[]OptionMod{{}},
+			[]string{"mod", "edit"},
+			nil,
+			false,
+		},
+		{
+			[]OptionMod{
+				{Replace: map[string]string{"github.com/b/lib": "../lib", "github.com/a/lib@v1.0.0": "github.com/fork/lib@v1.0.1"}},
+				{Require: map[string]string{"golang.org/x/net": "v0.20.0"}, Replace: map[string]string{"github.com/b/lib": "./third_party/lib"}},
+			},
+			[]string{"mod", "edit",
+				"-replace=github.com/a/lib@v1.0.0=github.com/fork/lib@v1.0.1",
+				"-replace=github.com/b/lib=" + filepath.Join(project, "third_party/lib"),
+				"-require=golang.org/x/net@v0.20.0"},
+			[]string{"mod", "download", "github.com/a/lib", "github.com/b/lib", "golang.org/x/net"},
+			false,
+		},
+		{
+			//arch层追加exclude，不覆盖all_platform层
+			[]OptionMod{{Exclude: []string{"github.com/bad/lib@v1.0.0"}}, {Exclude: []string{"github.com/bad/lib@v1.0.0", "github.com/bad/lib@v1.0.1"}}},
+			[]string{"mod", "edit", "-exclude=github.com/bad/lib@v1.0.0", "-exclude=github.com/bad/lib@v1.0.1"},
+			nil,
+			false,
+		},
+		{
+			[]OptionMod{{Exclude: []string{"github.com/bad/lib"}}},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for i, c := range cases {
+		mod := OptionMod{}
+		for _, layer := range c.layers {
+			mod.Patch(layer)
+		}
+		edit, err := mod.EditArgs(project)
+		if (err != nil) != c.err {
+			t.Errorf("case %d: err %v", i, err)
+			continue
+		}
+		if !c.err && !reflect.DeepEqual(edit, c.edit) {
+			t.Errorf("case %d: edit %q, want %q", i, edit, c.edit)
+		}
+		if download := mod.DownloadArgs(); !reflect.DeepEqual(download, c.download) {
+			t.Errorf("case %d: download %q, want %q", i, download, c.download)
+		}
+	}
+}
@@ -2,11 +2,17 @@ package options
 
 // Options 每对平台架构的具体设置
 type Options struct {
+	When        string         `toml:"when"` //CEL条件，满足时整个选项块才生效
 	Builder     OptionBuilder  `toml:"builder"`
 	Output      OptionOutput   `toml:"output"`
 	ReplaceRule OptionReplace  `toml:"replace"`
 	Docker      OptionDocker   `toml:"docker"`
 	SSH         OptionSSHBuild `toml:"ssh"`
+	Hooks       OptionHooks    `toml:"hooks"`
+	Mod         OptionMod      `toml:"mod"`
+	Generate    OptionGenerate `toml:"generate"`
+	Inject      OptionInject   `toml:"inject"`
+	Verify      OptionVerify   `toml:"verify"`
 }
 
 // Patch 对之前的选项进行补充
@@ -16,5 +22,10 @@ func (opt *Options) Patch(patchOpt Options) Options {
 	opt.SSH = opt.SSH.Patch(patchOpt.SSH)
 	opt.Output = opt.Output.Patch(patchOpt.Output)
 	opt.Builder = opt.Builder.Patch(patchOpt.Builder)
+	opt.Hooks = opt.Hooks.Patch(patchOpt.Hooks)
+	opt.Mod = opt.Mod.Patch(patchOpt.Mod)
+	opt.Generate = opt.Generate.Patch(patchOpt.Generate)
+	opt.Inject = opt.Inject.Patch(patchOpt.Inject)
+	opt.Verify = opt.Verify.Patch(patchOpt.Verify)
 	return *opt
 }
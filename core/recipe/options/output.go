@@ -1,15 +1,32 @@
 package options
 
+// DebugSymbolsSeparate 编译保留符号的产物，符号另存后发布去除符号的副本
+const DebugSymbolsSeparate = "separate"
+
 type OptionOutput struct {
-	Path string          `toml:"path"`
-	Zip  OptionZIP       `toml:"zip"`
-	SSH  OptionSSHOutput `toml:"ssh"`
+	Path string `toml:"path"`
+	Name string `toml:"name"` //输出文件名模板，可用变量Recipe Brand Platform Arch Variant Tag，path优先
+
+	DebugSymbols string `toml:"debug_symbols"` //"separate"时在输出目录的symbols中按Go build ID保存带符号的产物
+	StripTool    string `toml:"strip_tool"`    //去除符号使用的objcopy，默认依次尝试llvm-objcopy与objcopy
+
+	Zip OptionZIP       `toml:"zip"`
+	SSH OptionSSHOutput `toml:"ssh"`
 }
 
 func (oo *OptionOutput) Patch(patchOp OptionOutput) OptionOutput {
 	if patchOp.Path != "" {
 		oo.Path = patchOp.Path
 	}
+	if patchOp.Name != "" {
+		oo.Name = patchOp.Name
+	}
+	if patchOp.DebugSymbols != "" {
+		oo.DebugSymbols = patchOp.DebugSymbols
+	}
+	if patchOp.StripTool != "" {
+		oo.StripTool = patchOp.StripTool
+	}
 	oo.Zip = oo.Zip.Patch(patchOp.Zip)
 	oo.SSH = oo.SSH.Patch(patchOp.SSH)
 	return *oo
@@ -19,6 +36,7 @@ type OptionZIP struct {
 	Source   string `toml:"source"`
 	Dest     string `toml:"dest"`
 	Password string `toml:"password"`
+	When     string `toml:"when"`
 }
 
 func (oz *OptionZIP) Patch(patchOz OptionZIP) OptionZIP {
@@ -42,6 +60,7 @@ type OptionSSHOutput struct {
 	OptionSSH
 	Source string `toml:"source"`
 	Dest   string `toml:"dest"`
+	When   string `toml:"when"`
 }
 
 func (oso *OptionSSHOutput) Patch(patchOpt OptionSSHOutput) OptionSSHOutput {
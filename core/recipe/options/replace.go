@@ -1,30 +1,162 @@
 package options
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"sort"
 
 	"github.com/B9O2/filefinder"
 )
 
 // OptionReplace 替换选项
 type OptionReplace struct {
-	Dependency      map[string]string `toml:"dependency"`
-	Text            map[string]string `toml:"text"`
-	Dirs            []string          `toml:"dir_rules"`
-	FileNameRegexps []string          `toml:"file_regexps"`
+	Dependency      map[string]string   `toml:"dependency"`
+	Module          map[string]string   `toml:"module"` //重命名模块，同时修改导入路径、go.mod与vendor
+	Text            map[string]string   `toml:"text"`
+	Regex           map[string]string   `toml:"regex"`      //正则替换，替换内容中可以使用$1、${name}引用分组
+	Template        []string            `toml:"template"`   //以text/template渲染的文件(glob)
+	Rules           []OptionReplaceStep `toml:"rules"`      //按顺序执行的替换规则
+	ExpectMin       *int                `toml:"expect_min"` //每条规则至少匹配的次数，规则自身设置时以规则为准
+	ExpectMax       *int                `toml:"expect_max"`
+	Dirs            []string            `toml:"dir_rules"`
+	FileNameRegexps []string            `toml:"file_regexps"`
+	When            string              `toml:"when"`
 }
+
+// OptionReplaceStep replace.rules中的一条规则，text、regex、template三选一
+type OptionReplaceStep struct {
+	Text     string   `toml:"text"`
+	Regex    string   `toml:"regex"`
+	Template string   `toml:"template"` //渲染的文件(glob)
+	With     string   `toml:"with"`     //text与regex的替换内容
+	Files    []string `toml:"files"`    //限定文件(glob)，为空时使用dir_rules与file_regexps的范围
+
+	ExpectMin *int `toml:"expect_min"` //匹配次数少于该值时编译失败
+	ExpectMax *int `toml:"expect_max"` //匹配次数多于该值时编译失败
+}
+
+// 替换规则类型
+const (
+	ReplaceText     = "text"
+	ReplaceRegex    = "regex"
+	ReplaceTemplate = "template"
+)
+
+// ReplaceStep 解析后的替换规则
+type ReplaceStep struct {
+	Kind   string
+	Old    string //text的原文、regex的表达式或template的文件glob
+	New    string
+	Regexp *regexp.Regexp `json:"-"`
+	Files  []string
+
+	ExpectMin, ExpectMax *int
+}
+
+// CheckMatches 检查匹配次数是否在expect_min与expect_max之间
+func (rs ReplaceStep) CheckMatches(matches int) error {
+	if rs.ExpectMin != nil && matches < *rs.ExpectMin {
+		return fmt.Errorf("replace %s matched %d times, expected at least %d", rs.Name(), matches, *rs.ExpectMin)
+	}
+	if rs.ExpectMax != nil && matches > *rs.ExpectMax {
+		return fmt.Errorf("replace %s matched %d times, expected at most %d", rs.Name(), matches, *rs.ExpectMax)
+	}
+	return nil
+}
+
+// Name 规则的描述
+func (rs ReplaceStep) Name() string {
+	if rs.Kind == ReplaceTemplate {
+		return fmt.Sprintf("template %s", rs.Old)
+	}
+	return fmt.Sprintf("%s %q", rs.Kind, rs.Old)
+}
+
+func (ors OptionReplaceStep) parse() (ReplaceStep, error) {
+	step := ReplaceStep{New: ors.With, Files: ors.Files, ExpectMin: ors.ExpectMin, ExpectMax: ors.ExpectMax}
+	set := 0
+	if ors.Text != "" {
+		step.Kind, step.Old = ReplaceText, ors.Text
+		set++
+	}
+	if ors.Regex != "" {
+		step.Kind, step.Old = ReplaceRegex, ors.Regex
+		set++
+	}
+	if ors.Template != "" {
+		step.Kind, step.Old = ReplaceTemplate, ors.Template
+		set++
+	}
+	if set != 1 {
+		return step, errors.New("replace rule needs exactly one of text, regex and template")
+	}
+	if step.Kind == ReplaceRegex {
+		re, err := regexp.Compile(step.Old)
+		if err != nil {
+			return step, err
+		}
+		step.Regexp = re
+	}
+	return step, nil
+}
+
 type ReplaceRule struct {
 	DependencyReplace map[string]string
+	ModuleReplace     map[string]string
 	ReplacementWords  map[string]string
+	Steps             []ReplaceStep //依次执行的文本替换
 	Range             *filefinder.SearchRule
 }
 
 func (orr *OptionReplace) ParseReplaceRule() (ReplaceRule, error) {
 	r := ReplaceRule{
 		DependencyReplace: orr.Dependency,
+		ModuleReplace:     orr.Module,
 		ReplacementWords:  orr.Text,
 	}
 
+	//text按原文从长到短、regex按表达式排序，结果不依赖map的遍历顺序
+	var words []string
+	for old := range orr.Text {
+		words = append(words, old)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	var steps []OptionReplaceStep
+	for _, old := range words {
+		steps = append(steps, OptionReplaceStep{Text: old, With: orr.Text[old]})
+	}
+	var patterns []string
+	for pattern := range orr.Regex {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		steps = append(steps, OptionReplaceStep{Regex: pattern, With: orr.Regex[pattern]})
+	}
+	for _, glob := range orr.Template {
+		steps = append(steps, OptionReplaceStep{Template: glob})
+	}
+	steps = append(steps, orr.Rules...)
+	for _, s := range steps {
+		if s.ExpectMin == nil {
+			s.ExpectMin = orr.ExpectMin
+		}
+		if s.ExpectMax == nil {
+			s.ExpectMax = orr.ExpectMax
+		}
+		step, err := s.parse()
+		if err != nil {
+			return r, err
+		}
+		r.Steps = append(r.Steps, step)
+	}
+
 	var fileNameRegexps []*regexp.Regexp
 
 	for _, fileNameRegexp := range orr.FileNameRegexps {
@@ -56,11 +188,43 @@ func (orr *OptionReplace) Patch(por OptionReplace) OptionReplace {
 		orr.Dependency[k] = v
 	}
 
+	if orr.Module == nil {
+		orr.Module = map[string]string{}
+	}
+	for k, v := range por.Module {
+		orr.Module[k] = v
+	}
+
 	if orr.Text == nil {
 		orr.Text = map[string]string{}
 	}
 	for k, v := range por.Text {
 		orr.Text[k] = v
 	}
+
+	if orr.Regex == nil {
+		orr.Regex = map[string]string{}
+	}
+	for k, v := range por.Regex {
+		orr.Regex[k] = v
+	}
+	if len(por.Template) > 0 {
+		orr.Template = por.Template
+	}
+	if len(por.Rules) > 0 {
+		orr.Rules = por.Rules
+	}
+	if por.ExpectMin != nil {
+		orr.ExpectMin = por.ExpectMin
+	}
+	if por.ExpectMax != nil {
+		orr.ExpectMax = por.ExpectMax
+	}
+	if len(por.Dirs) > 0 {
+		orr.Dirs = por.Dirs
+	}
+	if len(por.FileNameRegexps) > 0 {
+		orr.FileNameRegexps = por.FileNameRegexps
+	}
 	return *orr
 }
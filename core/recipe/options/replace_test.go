@@ -0,0 +1,38 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestParseReplaceRuleOrder(t *testing.T) {
+	orr := OptionReplace{
+		Text:     map[string]string{"go": "x", "google": "apple", "golang": "y"},
+		Regex:    map[string]string{`https://([a-z]+)\.example\.com`: "https://$1.example.org"},
+		Template: []string{"config/*.yaml"},
+		Rules: []OptionReplaceStep{
+			{Regex: `v(\d+)`, With: "version-$1", Files: []string{"**/*.txt"}},
+			{Text: "a", With: "b"},
+		},
+	}
+	r, err := orr.ParseReplaceRule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`text "golang"`, `text "google"`, `text "go"`, `regex "https://([a-z]+)\\.example\\.com"`, "template config/*.yaml", `regex "v(\\d+)"`, `text "a"`}
+	if len(r.Steps) != len(want) {
+		t.Fatalf("%d steps, want %d", len(r.Steps), len(want))
+	}
+	for i, step := range r.Steps {
+		if step.Name() != want[i] {
+			t.Errorf("step %d: %s, want %s", i, step.Name(), want[i])
+		}
+	}
+	if got := string(r.Steps[3].Regexp.ReplaceAll([]byte("https://api.example.com/v1"), []byte(r.Steps[3].New))); got != "https://api.example.org/v1" {
+		t.Errorf("regex replaced to %s", got)
+	}
+
+	orr = OptionReplace{Rules: []OptionReplaceStep{{Text: "a", Regex: "b"}}}
+	if _, err = orr.ParseReplaceRule(); err == nil {
+		t.Error("rule with both text and regex accepted")
+	}
+}
@@ -39,7 +39,8 @@ func (os *OptionSSH) Patch(patchOpt OptionSSH) OptionSSH {
 
 type OptionSSHBuild struct {
 	OptionSSH
-	Temp string `toml:"temp"`
+	Temp    string `toml:"temp"`
+	GoCache string `toml:"gocache"` //远程主机上持久化Go缓存的目录，"off"关闭
 }
 
 func (osb *OptionSSHBuild) Patch(patchOpt OptionSSHBuild) OptionSSHBuild {
@@ -47,6 +48,9 @@ func (osb *OptionSSHBuild) Patch(patchOpt OptionSSHBuild) OptionSSHBuild {
 	if patchOpt.Temp != "" {
 		osb.Temp = patchOpt.Temp
 	}
+	if patchOpt.GoCache != "" {
+		osb.GoCache = patchOpt.GoCache
+	}
 	return *osb
 }
 
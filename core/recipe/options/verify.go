@@ -0,0 +1,76 @@
+package options
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// OptionVerify 对编译产物与ZIP中的文件进行检查
+type OptionVerify struct {
+	ForbiddenStrings []string `toml:"forbidden_strings"` //产物中不允许出现的字符串
+	ForbiddenRegexps []string `toml:"forbidden_regexps"` //产物中不允许出现的正则表达式
+	IgnoreCase       bool     `toml:"ignore_case"`       //forbidden_strings忽略大小写
+
+	SkipFormat bool   `toml:"skip_format"` //不检查产物的文件格式、平台与架构
+	Static     bool   `toml:"static"`      //要求静态链接
+	Stripped   bool   `toml:"stripped"`    //要求不含符号表
+	GoVersion  string `toml:"go_version"`  //要求的Go版本，如"go1.22"匹配go1.22.x
+	MainModule string `toml:"main_module"` //要求的主模块路径，go编译器默认为影子项目的模块
+}
+
+func (ov *OptionVerify) Patch(patchOpt OptionVerify) OptionVerify {
+	if len(patchOpt.ForbiddenStrings) > 0 {
+		ov.ForbiddenStrings = patchOpt.ForbiddenStrings
+	}
+	if len(patchOpt.ForbiddenRegexps) > 0 {
+		ov.ForbiddenRegexps = patchOpt.ForbiddenRegexps
+	}
+	if patchOpt.IgnoreCase {
+		ov.IgnoreCase = true
+	}
+	if patchOpt.SkipFormat {
+		ov.SkipFormat = true
+	}
+	if patchOpt.Static {
+		ov.Static = true
+	}
+	if patchOpt.Stripped {
+		ov.Stripped = true
+	}
+	if patchOpt.GoVersion != "" {
+		ov.GoVersion = patchOpt.GoVersion
+	}
+	if patchOpt.MainModule != "" {
+		ov.MainModule = patchOpt.MainModule
+	}
+	return *ov
+}
+
+// ForbiddenPattern 一项禁止出现的内容，Name为配置中的原始写法
+type ForbiddenPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// Patterns 编译所有禁止出现的字符串与正则表达式
+func (ov *OptionVerify) Patterns() ([]ForbiddenPattern, error) {
+	var patterns []ForbiddenPattern
+	for _, s := range ov.ForbiddenStrings {
+		if s == "" {
+			return nil, fmt.Errorf("verify: empty forbidden string")
+		}
+		expr := regexp.QuoteMeta(s)
+		if ov.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		patterns = append(patterns, ForbiddenPattern{Name: s, Regexp: regexp.MustCompile(expr)})
+	}
+	for _, expr := range ov.ForbiddenRegexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("verify: %w", err)
+		}
+		patterns = append(patterns, ForbiddenPattern{Name: expr, Regexp: re})
+	}
+	return patterns, nil
+}
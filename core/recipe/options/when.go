@@ -0,0 +1,37 @@
+package options
+
+// Resolve 对选项中带有when条件的块求值，条件不满足的块被清空。
+// match的part为块在选项中的位置，选项整体为""
+func (opt Options) Resolve(match func(part, expr string) (bool, error)) (Options, error) {
+	check := func(part, expr string) (bool, error) {
+		if expr == "" {
+			return true, nil
+		}
+		return match(part, expr)
+	}
+
+	if ok, err := check("", opt.When); err != nil || !ok {
+		return Options{}, err
+	}
+	if ok, err := check("replace", opt.ReplaceRule.When); err != nil {
+		return opt, err
+	} else if !ok {
+		opt.ReplaceRule = OptionReplace{}
+	}
+	if ok, err := check("hooks", opt.Hooks.When); err != nil {
+		return opt, err
+	} else if !ok {
+		opt.Hooks = OptionHooks{}
+	}
+	if ok, err := check("output.zip", opt.Output.Zip.When); err != nil {
+		return opt, err
+	} else if !ok {
+		opt.Output.Zip = OptionZIP{}
+	}
+	if ok, err := check("output.ssh", opt.Output.SSH.When); err != nil {
+		return opt, err
+	} else if !ok {
+		opt.Output.SSH = OptionSSHOutput{}
+	}
+	return opt, nil
+}
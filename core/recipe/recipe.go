@@ -3,6 +3,7 @@ package recipe
 import (
 	_ "embed"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/B9O2/bake/core/recipe/options"
@@ -30,122 +31,197 @@ func (p ArchOption) AllArchOption() options.Options {
 }
 
 type Recipe struct {
-	Debug       bool       `toml:"debug"`
-	Desc        string     `toml:"desc"`
-	Entrance    string     `toml:"entrance"`
-	Output      string     `toml:"output"`
-	Pairs       []string   `toml:"pairs"`
-	AllPlatform ArchOption `toml:"all_platform"`
-	Darwin      ArchOption `toml:"darwin"`
-	Linux       ArchOption `toml:"linux"`
-	Windows     ArchOption `toml:"windows"`
+	Debug       bool               `toml:"debug"`
+	Desc        string             `toml:"desc"`
+	Entrance    string             `toml:"entrance"`
+	Output      string             `toml:"output"`
+	Pairs       []string           `toml:"pairs"`
+	Brand       string             `toml:"brand"` //默认使用的品牌，可以被--brand覆盖
+	Copy        options.OptionCopy `toml:"copy"`
+	Checks      []string           `toml:"checks"`
+	AllPlatform ArchOption         `toml:"all_platform"`
+	Darwin      ArchOption         `toml:"darwin"`
+	Linux       ArchOption         `toml:"linux"`
+	Windows     ArchOption         `toml:"windows"`
 }
 
-func (r Recipe) ToConfig() (Config, error) {
+// PlatformOption 平台对应的设置，all_platform为全平台设置
+func (r Recipe) PlatformOption(platform string) ArchOption {
+	switch platform {
+	case "all_platform":
+		return r.AllPlatform
+	case "darwin":
+		return r.Darwin
+	case "linux":
+		return r.Linux
+	case "windows":
+		return r.Windows
+	}
+	return nil
+}
+
+// ToConfig 为每个目标平台架构依次应用全平台、特定平台的设置，每个平台先应用all_arch再应用具体架构，
+// brand不为nil时最后应用品牌设置。带有when条件的选项块只在条件满足时生效
+func (r Recipe) ToConfig(ctx CondContext, brand *Brand) (Config, error) {
 	cfg := Config{
 		Debug:  r.Debug,
 		Output: "bake_bin",
+		Copy:   r.Copy,
+		Checks: r.Checks,
+		Brand:  ctx.Brand,
 	}
-	mid := map[string]map[string]options.Options{}
 	if len(r.Pairs) <= 0 {
 		r.Pairs = strings.Split(AllPairs, "\n")
 	}
-	//在中间结构mid中初始化所有目标平台架构
+	evaluator, err := NewEvaluator()
+	if err != nil {
+		return cfg, err
+	}
+
+	visited := map[string]bool{}
 	for _, pair := range r.Pairs {
-		if platform, arch, ok := strings.Cut(pair, "/"); ok {
-			if _, ok = mid[platform]; !ok {
-				mid[platform] = map[string]options.Options{}
+		platform, arch, variant, ok := ParsePair(pair)
+		if !ok || visited[pair] {
+			continue
+		}
+		visited[pair] = true
+		archKeys := []string{"all_arch", arch}
+		if variant != "" {
+			if _, _, err = VariantEnv(arch, variant); err != nil {
+				return cfg, err
 			}
-			mid[platform][arch] = options.Options{}
+			//linux/arm/v7对应配置中的linux.arm_v7
+			archKeys = append(archKeys, arch+"_"+variant)
 		}
-	}
 
-	PatchOption := func(platform string, ao ArchOption) {
-		if _, ok := mid[platform]; !ok {
-			return
+		pairCtx := ctx
+		pairCtx.Platform, pairCtx.Arch, pairCtx.Variant = platform, arch, variant
+		//依次应用的选项层
+		type layer struct {
+			where  string
+			option options.Options
 		}
-		opt := ao.AllArchOption()
-		for platform, archOption := range mid {
-			for midArch, midOption := range archOption {
-				mid[platform][midArch] = midOption.Patch(opt)
+		var layers []layer
+		for _, p := range []string{"all_platform", platform} {
+			ao := r.PlatformOption(p)
+			for _, a := range archKeys {
+				if layerOption, ok := ao[a]; ok {
+					layers = append(layers, layer{p + "." + a, layerOption})
+				}
 			}
 		}
-		ao.Range(func(arch string, option options.Options) bool {
-			if _, ok := mid[platform][arch]; ok {
-				midOption := mid[platform][arch]
-				mid[platform][arch] = midOption.Patch(option)
-			}
-			return true
-		})
-	}
-
-	//遍历所有目标平台架构，应用全平台设置
-	for platform := range mid {
-		PatchOption(platform, r.AllPlatform)
-	}
-
-	//分别应用特殊平台设置
-	PatchOption("darwin", r.Darwin)
-	PatchOption("linux", r.Linux)
-	PatchOption("windows", r.Windows)
+		if brand != nil {
+			layers = append(layers, layer{"brand." + ctx.Brand, brand.Options})
+		}
 
-	for platform, archOption := range mid {
-		for arch, option := range archOption {
-			rr, err := option.ReplaceRule.ParseReplaceRule()
+		var conditions []Condition
+		var applied []string
+		option := options.Options{}
+		for _, l := range layers {
+			skipped := false
+			layerOption, err := l.option.Resolve(func(part, expr string) (bool, error) {
+				matched, err := evaluator.Eval(expr, pairCtx)
+				if err != nil {
+					return false, fmt.Errorf("%s: %w", l.where, err)
+				}
+				if part != "" {
+					part = l.where + "." + part
+				} else {
+					part, skipped = l.where, !matched
+				}
+				conditions = append(conditions, Condition{Where: part, Expr: expr, Matched: matched})
+				return matched, nil
+			})
 			if err != nil {
 				return cfg, err
 			}
-
-			bp := BuildPair{
-				Platform: platform,
-				Arch:     arch,
-				Rule:     rr,
-				Remote:   targets.NewLocalTarget(platform, arch), //默认本地编译
-				Builder: options.OptionBuilder{
-					Path: "go",
-					Args: []string{
-						"-trimpath",
-						"-ldflags",
-						"-w -s",
-					},
-					Env: map[string]string{},
-				},
+			if !skipped {
+				applied = append(applied, l.where)
 			}
+			option = option.Patch(layerOption)
+		}
+
+		rr, err := option.ReplaceRule.ParseReplaceRule()
+		if err != nil {
+			return cfg, err
+		}
 
-			bp.Output.Patch(option.Output)
-			bp.Builder.Patch(option.Builder)
-
-			//配置了Docker目标
-			if option.Docker.Host != "" {
-				bp.Remote = targets.NewDockerTarget(option.Docker.Host,
-					option.Docker.Container,
-					option.Docker.Image,
-					option.Docker.Temp,
-					platform,
-					arch)
+		bp := BuildPair{
+			Recipe:     ctx.Recipe,
+			Brand:      ctx.Brand,
+			Conditions: conditions,
+			Layers:     applied,
+			Platform:   platform,
+			Arch:       arch,
+			Variant:    variant,
+			Rule:       rr,
+			Builder: options.OptionBuilder{
+				Env: map[string]string{},
+			},
+		}
+
+		bp.Output.Patch(option.Output)
+		if bp.Output.Path == "" && bp.Output.Name != "" {
+			if bp.Output.Path, err = bp.renderName(bp.Output.Name); err != nil {
+				return cfg, err
 			}
+		}
+		bp.Builder.Patch(option.Builder)
+		switch bp.Output.DebugSymbols {
+		case "":
+		case options.DebugSymbolsSeparate:
+			//编译时保留符号，发布前再去除
+			strip := false
+			bp.Builder.Strip = &strip
+		default:
+			return cfg, fmt.Errorf("%s: unknown debug_symbols '%s'", bp.Tag(), bp.Output.DebugSymbols)
+		}
+		if err = bp.Builder.ValidateBuildMode(platform, arch); err != nil {
+			return cfg, fmt.Errorf("%s: %w", bp.Tag(), err)
+		}
+		bp.Hooks.Patch(option.Hooks)
+		bp.Mod.Patch(option.Mod)
+		bp.Generate.Patch(option.Generate)
+		bp.Inject.Patch(option.Inject)
+		bp.Verify.Patch(option.Verify)
+		if _, err = bp.Verify.Patterns(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", bp.Tag(), err)
+		}
 
-			//配置了SSH目标
-			if option.SSH.Host != "" {
-				sshCfg := &utils.SSHAuthConfig{
-					User:               option.SSH.User,
-					Password:           option.SSH.Password,
-					PrivateKeyPath:     option.SSH.PrivateKeyPath,
-					PrivateKeyPassword: option.SSH.PrivateKeyPassword,
-				}
+		bp.Remote = bp.LocalTarget() //默认本地编译
+
+		//配置了Docker目标
+		if option.Docker.Host != "" {
+			bp.Remote = targets.NewDockerTarget(option.Docker.Host,
+				option.Docker.Container,
+				option.Docker.Image,
+				option.Docker.Temp,
+				option.Docker.GoCache,
+				platform,
+				arch)
+		}
 
-				bp.Remote = targets.NewSSHTargetWithConfig(
-					option.SSH.Host,
-					option.SSH.Port,
-					option.SSH.Temp,
-					platform,
-					arch,
-					sshCfg,
-				)
+		//配置了SSH目标
+		if option.SSH.Host != "" {
+			sshCfg := &utils.SSHAuthConfig{
+				User:               option.SSH.User,
+				Password:           option.SSH.Password,
+				PrivateKeyPath:     option.SSH.PrivateKeyPath,
+				PrivateKeyPassword: option.SSH.PrivateKeyPassword,
 			}
 
-			cfg.Targets = append(cfg.Targets, bp)
+			bp.Remote = targets.NewSSHTargetWithConfig(
+				option.SSH.Host,
+				option.SSH.Port,
+				option.SSH.Temp,
+				option.SSH.GoCache,
+				platform,
+				arch,
+				sshCfg,
+			)
 		}
+
+		cfg.Targets = append(cfg.Targets, bp)
 	}
 
 	if r.Entrance == "" {
@@ -161,6 +237,14 @@ func (r Recipe) ToConfig() (Config, error) {
 	return cfg, nil
 }
 
+// Brand 品牌，一组在配方所有设置之后应用于每个编译目标的选项，可以用when限定平台
+type Brand struct {
+	Desc string `toml:"desc"`
+	options.Options
+}
+
 type RecipeDoc struct {
-	Recipes map[string]Recipe `toml:"recipes"`
+	Cache   options.OptionCache `toml:"cache"`
+	Brands  map[string]Brand    `toml:"brands"`
+	Recipes map[string]Recipe   `toml:"recipes"`
 }
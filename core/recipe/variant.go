@@ -0,0 +1,53 @@
+package recipe
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// variantEnvs 架构对应的微架构环境变量及可用的取值
+var variantEnvs = map[string]struct {
+	name   string
+	values []string
+}{
+	"arm":      {"GOARM", []string{"5", "6", "7"}},
+	"arm64":    {"GOARM64", nil}, //如"v8.0"、"v9.0,lse"
+	"amd64":    {"GOAMD64", []string{"v1", "v2", "v3", "v4"}},
+	"386":      {"GO386", []string{"sse2", "softfloat"}},
+	"mips":     {"GOMIPS", []string{"hardfloat", "softfloat"}},
+	"mipsle":   {"GOMIPS", []string{"hardfloat", "softfloat"}},
+	"mips64":   {"GOMIPS64", []string{"hardfloat", "softfloat"}},
+	"mips64le": {"GOMIPS64", []string{"hardfloat", "softfloat"}},
+	"ppc64":    {"GOPPC64", []string{"power8", "power9", "power10"}},
+	"ppc64le":  {"GOPPC64", []string{"power8", "power9", "power10"}},
+	"riscv64":  {"GORISCV64", []string{"rva20u64", "rva22u64"}},
+}
+
+// VariantEnv 架构变体对应的环境变量，例如arm/v7为GOARM=7，amd64/v3为GOAMD64=v3
+func VariantEnv(arch, variant string) (string, string, error) {
+	v, ok := variantEnvs[arch]
+	if !ok {
+		return "", "", fmt.Errorf("arch %s has no variants", arch)
+	}
+	value := variant
+	if arch == "arm" {
+		value = strings.TrimPrefix(variant, "v")
+	}
+	if v.values != nil && !slices.Contains(v.values, value) {
+		return "", "", fmt.Errorf("unknown variant %s for arch %s", variant, arch)
+	}
+	return v.name, value, nil
+}
+
+// ParsePair 解析"平台/架构"或"平台/架构/变体"
+func ParsePair(pair string) (platform, arch, variant string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(pair), "/")
+	switch len(parts) {
+	case 2:
+		return parts[0], parts[1], "", parts[0] != "" && parts[1] != ""
+	case 3:
+		return parts[0], parts[1], parts[2], parts[0] != "" && parts[1] != "" && parts[2] != ""
+	}
+	return "", "", "", false
+}
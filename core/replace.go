@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/B9O2/filefinder"
+)
+
+// TemplateData replace.template渲染文件时可用的变量
+type TemplateData struct {
+	Recipe   string
+	Brand    string
+	Platform string
+	Arch     string
+	Variant  string
+	Tag      string
+	Name     string
+	Env      map[string]string //编译时的环境变量
+}
+
+func NewTemplateData(pair recipe.BuildPair) (TemplateData, error) {
+	env, err := pair.BuildEnv()
+	if err != nil {
+		return TemplateData{}, err
+	}
+	return TemplateData{
+		Recipe:   pair.Recipe,
+		Brand:    pair.Brand,
+		Platform: pair.Platform,
+		Arch:     pair.Arch,
+		Variant:  pair.Variant,
+		Tag:      pair.Tag(),
+		Name:     pair.Name(),
+		Env:      env,
+	}, nil
+}
+
+// replaceRange dir_rules与file_regexps限定的文件，没有限定时返回nil
+func (gb *GoBuilder) replaceRange(replaceRange *filefinder.SearchRule) (map[string]bool, error) {
+	if replaceRange == nil {
+		return nil, nil
+	}
+	db, err := filefinder.NewFileDB(gb.shadowPath)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]bool{}
+	for _, paths := range db.Search([]*filefinder.SearchRule{replaceRange})[replaceRange.RuleName] {
+		for _, filePath := range paths {
+			files[filepath.Clean(filePath)] = true
+		}
+	}
+	return files, nil
+}
+
+// stepMatch 判断规则是否作用于文件
+func stepMatch(step options.ReplaceStep, rel string, inRange bool) bool {
+	if step.Kind == options.ReplaceTemplate {
+		return utils.GlobMatch(step.Old, rel)
+	}
+	if len(step.Files) == 0 {
+		return inRange
+	}
+	for _, glob := range step.Files {
+		if utils.GlobMatch(glob, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyStep 对文件内容执行一条规则，返回替换后的内容与匹配次数
+func applyStep(step options.ReplaceStep, name string, content []byte, data TemplateData) ([]byte, int, error) {
+	switch step.Kind {
+	case options.ReplaceRegex:
+		matches := len(step.Regexp.FindAllIndex(content, -1))
+		if matches == 0 {
+			return content, 0, nil
+		}
+		return step.Regexp.ReplaceAll(content, []byte(step.New)), matches, nil
+	case options.ReplaceTemplate:
+		t, err := template.New(name).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, 0, err
+		}
+		var buf bytes.Buffer
+		if err = t.Execute(&buf, data); err != nil {
+			return nil, 0, err
+		}
+		return buf.Bytes(), 1, nil
+	}
+	matches := bytes.Count(content, []byte(step.Old))
+	if matches == 0 {
+		return content, 0, nil
+	}
+	return bytes.ReplaceAll(content, []byte(step.Old), []byte(step.New)), matches, nil
+}
+
+// ReplaceReport 一条替换规则的执行结果
+type ReplaceReport struct {
+	Step    options.ReplaceStep
+	Matches int
+	Files   []string //被修改的文件，相对影子项目
+}
+
+// FileReplace 按顺序对影子目录中的文件执行编译目标的替换规则。
+// 跳过.git、二进制文件与符号链接，保持文件权限，匹配次数不满足expect_min/expect_max时返回错误
+func (gb *GoBuilder) FileReplace(pair recipe.BuildPair) ([]ReplaceReport, error) {
+	rule := pair.Rule
+	if len(rule.Steps) == 0 {
+		return nil, nil
+	}
+	files, err := gb.replaceRange(rule.Range)
+	if err != nil {
+		return nil, err
+	}
+	data, err := NewTemplateData(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]ReplaceReport, len(rule.Steps))
+	for i, step := range rule.Steps {
+		reports[i].Step = step
+	}
+	err = filepath.WalkDir(gb.shadowPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(gb.shadowPath, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		inRange := files == nil || files[filepath.Clean(filePath)]
+
+		var original []byte
+		var content []byte
+		for i, step := range rule.Steps {
+			if !stepMatch(step, rel, inRange) {
+				continue
+			}
+			if original == nil {
+				if original, err = os.ReadFile(filePath); err != nil {
+					return err
+				}
+				if utils.IsBinary(original) {
+					return nil
+				}
+				content = original
+			}
+			replaced, matches, err := applyStep(step, rel, content, data)
+			if err != nil {
+				return fmt.Errorf("replace %s in %s: %w", step.Name(), rel, err)
+			}
+			reports[i].Matches += matches
+			if !bytes.Equal(replaced, content) {
+				reports[i].Files = append(reports[i].Files, rel)
+			}
+			content = replaced
+		}
+		if original == nil || bytes.Equal(original, content) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		Insp.Print(Text("Replaced", decorators.Green), Path(rel))
+		return os.WriteFile(filePath, content, info.Mode().Perm())
+	})
+	if err != nil {
+		return reports, err
+	}
+
+	var errs []error
+	for _, report := range reports {
+		Insp.Print(Text("Replace Rule"), Text(report.Step.Name(), decorators.Yellow),
+			Text(fmt.Sprintf("%d matches in %d files", report.Matches, len(report.Files)), decorators.Cyan))
+		if err = report.Step.CheckMatches(report.Matches); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return reports, errors.Join(errs...)
+}
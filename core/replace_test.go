@@ -0,0 +1,62 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/core/recipe/options"
+)
+
+func TestFileReplace(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":          "package main\n\nconst host = \"google.com\"\n",
+		"run.sh":           "#!/bin/sh\necho google\n",
+		"logo.png":         "google\x00google",
+		".git/config":      "url = google.com\n",
+		"config/app.yaml":  "platform: {{.Platform}}\n",
+		"config/keep.yaml": "platform: {{.Platform}}\n",
+	}
+	writeTree(t, root, files)
+	if err := os.Chmod(filepath.Join(root, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	one := 1
+	orr := options.OptionReplace{
+		Text:     map[string]string{"google": "apple"},
+		Template: []string{"config/app.yaml"},
+		Rules:    []options.OptionReplaceStep{{Text: "renamed-upstream", With: "x", ExpectMin: &one}},
+	}
+	rule, err := orr.ParseReplaceRule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb := &GoBuilder{shadowPath: root}
+	reports, err := gb.FileReplace(recipe.BuildPair{Platform: "linux", Arch: "amd64", Rule: rule})
+	if err == nil || !strings.Contains(err.Error(), "renamed-upstream") {
+		t.Errorf("expect_min not enforced: %v", err)
+	}
+	if reports[0].Matches != 2 || len(reports[0].Files) != 2 {
+		t.Errorf("text rule: %d matches in %v", reports[0].Matches, reports[0].Files)
+	}
+
+	for name, want := range map[string]string{
+		"main.go":          "package main\n\nconst host = \"apple.com\"\n",
+		"run.sh":           "#!/bin/sh\necho apple\n",
+		"logo.png":         files["logo.png"],
+		".git/config":      files[".git/config"],
+		"config/app.yaml":  "platform: linux\n",
+		"config/keep.yaml": files["config/keep.yaml"],
+	} {
+		if got := readTree(t, root, name); got != want {
+			t.Errorf("%s: %q, want %q", name, got, want)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(root, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh mode not preserved: %v %v", info.Mode(), err)
+	}
+}
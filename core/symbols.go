@@ -0,0 +1,283 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"debug/gosym"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe"
+	"github.com/B9O2/bake/utils"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+)
+
+// SymbolsDir 输出目录中保存带符号产物的目录
+const SymbolsDir = "symbols"
+
+var (
+	goBuildIDPrefix = []byte("\xff Go build ID: \"")
+	goBuildIDEnd    = []byte("\"\n \xff")
+	buildIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_\-]+(/[A-Za-z0-9_\-]+)*$`)
+	//不以+开头的十六进制地址，"+0x1d"是函数内偏移
+	addressPattern = regexp.MustCompile(`(^|[^+\w])(0x[0-9a-fA-F]+)`)
+)
+
+// ReadBuildID 读取产物中的Go build ID，ELF从.note.go.buildid读取，其余格式从代码段开头读取
+func ReadBuildID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if f, err := elf.NewFile(bytes.NewReader(data)); err == nil {
+		if sect := f.Section(".note.go.buildid"); sect != nil {
+			note, err := sect.Data()
+			if err != nil {
+				return "", err
+			}
+			//namesz descsz type "Go\x00\x00" desc
+			if len(note) >= 16 {
+				descSize := f.ByteOrder.Uint32(note[4:8])
+				if uint32(len(note)) >= 16+descSize {
+					return string(note[16 : 16+descSize]), nil
+				}
+			}
+		}
+	}
+	start := bytes.Index(data, goBuildIDPrefix)
+	if start < 0 {
+		return "", fmt.Errorf("%s: no Go build ID", path)
+	}
+	start += len(goBuildIDPrefix) - 1 //保留开头的引号
+	end := bytes.Index(data[start:], goBuildIDEnd)
+	if end < 0 {
+		return "", fmt.Errorf("%s: malformed Go build ID", path)
+	}
+	return strconv.Unquote(string(data[start : start+end+1]))
+}
+
+// SymbolStore 按Go build ID保存带符号的产物
+type SymbolStore struct {
+	dir string
+}
+
+func (ss SymbolStore) entryPath(buildID string) (string, error) {
+	if !buildIDPattern.MatchString(buildID) {
+		return "", fmt.Errorf("invalid build ID '%s'", buildID)
+	}
+	return filepath.Join(ss.dir, filepath.FromSlash(buildID)), nil
+}
+
+// Save 复制带符号的产物，返回保存的路径
+func (ss SymbolStore) Save(buildID, binaryPath string) (string, error) {
+	entry, err := ss.entryPath(buildID)
+	if err != nil {
+		return "", err
+	}
+	dest := filepath.Join(entry, filepath.Base(binaryPath))
+	return dest, utils.CopyFile(binaryPath, dest, 0644)
+}
+
+// Find 查找build ID对应的带符号产物
+func (ss SymbolStore) Find(buildID string) (string, error) {
+	entry, err := ss.entryPath(buildID)
+	if err != nil {
+		return "", err
+	}
+	items, err := os.ReadDir(entry)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no symbols for build ID %s in %s", buildID, ss.dir)
+		}
+		return "", err
+	}
+	for _, item := range items {
+		if item.Type().IsRegular() {
+			return filepath.Join(entry, item.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("no symbols for build ID %s in %s", buildID, ss.dir)
+}
+
+func NewSymbolStore(dir string) SymbolStore {
+	return SymbolStore{dir: dir}
+}
+
+// stripTool 去除符号使用的命令
+func stripTool(tool string) (string, error) {
+	if tool != "" {
+		return tool, nil
+	}
+	for _, name := range []string{"llvm-objcopy", "objcopy"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("llvm-objcopy or objcopy is required for debug_symbols, set output.strip_tool")
+}
+
+// SeparateSymbols 将带符号的产物保存到输出目录的symbols中，并将产物替换为去除符号的副本
+func SeparateSymbols(pair recipe.BuildPair, outputDir, binaryPath string) (string, error) {
+	buildID, err := ReadBuildID(binaryPath)
+	if err != nil {
+		return "", err
+	}
+	tool, err := stripTool(pair.Output.StripTool)
+	if err != nil {
+		return "", err
+	}
+	saved, err := NewSymbolStore(filepath.Join(outputDir, SymbolsDir)).Save(buildID, binaryPath)
+	if err != nil {
+		return "", err
+	}
+
+	stat, err := os.Stat(binaryPath)
+	if err != nil {
+		return "", err
+	}
+	stripped := binaryPath + ".stripped"
+	cmd := exec.Command(tool, "--strip-all", binaryPath, stripped)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(stripped)
+		return "", fmt.Errorf("strip failed: %s %s", err, strings.TrimSpace(string(output)))
+	}
+	if err = os.Chmod(stripped, stat.Mode().Perm()); err != nil {
+		return "", err
+	}
+	if err = os.Rename(stripped, binaryPath); err != nil {
+		return "", err
+	}
+	Insp.Print(Text("Symbols Saved", decorators.Green), Text(buildID, decorators.Cyan), Path(saved))
+	return buildID, nil
+}
+
+// LoadLineTable 读取产物中的pclntab，PE格式需要符号表定位pclntab，因此需使用未去除符号的产物
+func LoadLineTable(path string) (*gosym.Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var textStart uint64
+	var pclntab []byte
+	switch DetectFormat(f) {
+	case FormatELF:
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if sect := ef.Section(".text"); sect != nil {
+			textStart = sect.Addr
+		}
+		sect := ef.Section(".gopclntab")
+		if sect == nil {
+			sect = ef.Section(".data.rel.ro.gopclntab") //PIE
+		}
+		if sect == nil {
+			return nil, errors.New("no .gopclntab section")
+		}
+		if pclntab, err = sect.Data(); err != nil {
+			return nil, err
+		}
+	case FormatMachO:
+		mf, err := macho.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if sect := mf.Section("__text"); sect != nil {
+			textStart = sect.Addr
+		}
+		sect := mf.Section("__gopclntab")
+		if sect == nil {
+			return nil, errors.New("no __gopclntab section")
+		}
+		if pclntab, err = sect.Data(); err != nil {
+			return nil, err
+		}
+	case FormatPE:
+		if textStart, pclntab, err = pePclntab(f); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported binary format", path)
+	}
+	return gosym.NewTable(nil, gosym.NewLineTable(pclntab, textStart))
+}
+
+// pePclntab 通过runtime.pclntab与runtime.epclntab符号定位pclntab
+func pePclntab(r io.ReaderAt) (uint64, []byte, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var imageBase uint64
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		imageBase = uint64(oh.ImageBase)
+	case *pe.OptionalHeader64:
+		imageBase = oh.ImageBase
+	}
+	var textStart uint64
+	if sect := f.Section(".text"); sect != nil {
+		textStart = imageBase + uint64(sect.VirtualAddress)
+	}
+	var start, end *pe.Symbol
+	for _, s := range f.Symbols {
+		switch s.Name {
+		case "runtime.pclntab":
+			start = s
+		case "runtime.epclntab":
+			end = s
+		}
+	}
+	if start == nil || end == nil || start.SectionNumber != end.SectionNumber || start.SectionNumber <= 0 || int(start.SectionNumber) > len(f.Sections) {
+		return 0, nil, errors.New("no runtime.pclntab symbol, symbols are stripped")
+	}
+	data, err := f.Sections[start.SectionNumber-1].Data()
+	if err != nil {
+		return 0, nil, err
+	}
+	if end.Value < start.Value || int(end.Value) > len(data) {
+		return 0, nil, errors.New("malformed runtime.pclntab symbol")
+	}
+	return textStart, data[start.Value:end.Value], nil
+}
+
+// Symbolize 为堆栈中的每个地址追加函数与源码位置
+func Symbolize(table *gosym.Table, trace io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(trace)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	w := bufio.NewWriter(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Fprintln(w, line)
+		for _, m := range addressPattern.FindAllStringSubmatch(line, -1) {
+			pc, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(m[2]), "0x"), 16, 64)
+			if err != nil {
+				continue
+			}
+			file, lineNo, fn := table.PCToLine(pc)
+			if fn == nil {
+				continue
+			}
+			fmt.Fprintf(w, "\t%s => %s %s:%d\n", m[2], fn.Name, file, lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSymbolize(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildID, err := ReadBuildID(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewSymbolStore(filepath.Join(t.TempDir(), SymbolsDir))
+	if _, err = store.Save(buildID, exe); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Find(buildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Find("../escape"); err == nil {
+		t.Error("invalid build ID accepted")
+	}
+
+	table, err := LoadLineTable(saved)
+	if err != nil {
+		t.Skipf("%s: %v", runtime.GOOS, err)
+	}
+	fn := table.LookupFunc("github.com/B9O2/bake/core.TestSymbolize")
+	if fn == nil {
+		t.Fatal("test function not in line table")
+	}
+	var out strings.Builder
+	trace := "pc=0x" + strconv.FormatUint(fn.Entry, 16) + " m=0\n\tmain.go:1 +0x1d\n"
+	if err = Symbolize(table, strings.NewReader(trace), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "=> github.com/B9O2/bake/core.TestSymbolize") || !strings.Contains(out.String(), "symbols_test.go") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if strings.Count(out.String(), "=>") != 1 {
+		t.Errorf("function offset symbolized:\n%s", out.String())
+	}
+}
@@ -14,11 +14,19 @@ import (
 	"github.com/B9O2/bake/utils"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/kballard/go-shellquote"
 	"github.com/moby/term"
 )
 
+// dockerGoCacheMount 缓存卷在容器中的挂载点
+const dockerGoCacheMount = "/bake-gocache"
+
+// dockerGoCacheDir 无法挂载缓存卷的已有容器中使用的缓存目录
+const dockerGoCacheDir = "/var/cache/bake-gocache"
+
 // DockerTarget todo docker远程目标
 type DockerTarget struct {
 	*BaseTarget
@@ -29,6 +37,9 @@ type DockerTarget struct {
 	containerID, imageID string
 	removeContainer      bool
 	stopContainer        bool
+	goCache              string //缓存卷名称
+	goCachePath          string //容器中的缓存目录
+	goVersion            string
 }
 
 func (dt *DockerTarget) InitAndConnect(string) error {
@@ -54,7 +65,16 @@ func (dt *DockerTarget) InitAndConnect(string) error {
 		return err
 	}
 	Insp.Print(Text("Docker Connected", decorators.Green), Text(info.Name, decorators.Cyan))
-	return nil
+	//上传影子项目前需要在容器中查询Go版本
+	return dt.CheckContainer()
+}
+
+// workDir 影子项目上传前在根目录执行命令
+func (dt *DockerTarget) workDir() string {
+	if dt.shadowPath == "" {
+		return ""
+	}
+	return dt.temp
 }
 
 func (dt *DockerTarget) Close() error {
@@ -87,13 +107,25 @@ func (dt *DockerTarget) Close() error {
 }
 
 func (dt *DockerTarget) Info() string {
-	return "Docker Build"
+	if dt.imageID != "" {
+		return fmt.Sprintf("Docker Build (image %s)", dt.imageID)
+	}
+	return fmt.Sprintf("Docker Build (container %s)", dt.containerID)
 }
 
 func (dt *DockerTarget) CheckContainer() error {
 	stats, err := dt.dc.ContainerInspect(dt.ctx, dt.containerID)
 	if err == nil {
 		Insp.Print(Text("Container Find", decorators.Green), Text(fmt.Sprintf("%s(%s)", stats.Name, stats.ID), decorators.Cyan))
+		//已有容器无法追加挂载，未挂载缓存卷时使用容器内的固定目录
+		if dt.goCache != "" {
+			dt.goCachePath = dockerGoCacheDir
+			for _, m := range stats.Mounts {
+				if m.Destination == dockerGoCacheMount {
+					dt.goCachePath = dockerGoCacheMount
+				}
+			}
+		}
 		if !stats.State.Running {
 			Insp.Print(Text("Container is not running, restarting...", decorators.Yellow))
 			if err = dt.dc.ContainerStart(dt.ctx, dt.containerID, container.StartOptions{}); err != nil {
@@ -127,10 +159,21 @@ func (dt *DockerTarget) CheckContainer() error {
 
 	Insp.Print(Text("Image pulled successfully", decorators.Green), Text(dt.imageID, decorators.Cyan))
 	//启动容器
+	var hostConfig *container.HostConfig
+	if dt.goCache != "" {
+		hostConfig = &container.HostConfig{
+			Mounts: []mount.Mount{{
+				Type:   mount.TypeVolume,
+				Source: dt.goCache,
+				Target: dockerGoCacheMount,
+			}},
+		}
+		dt.goCachePath = dockerGoCacheMount
+	}
 	resp, err := dt.dc.ContainerCreate(dt.ctx, &container.Config{
 		Image: dt.imageID,
 		Cmd:   []string{"tail", "-f", "/dev/null"},
-	}, nil, nil, nil, "")
+	}, hostConfig, nil, nil, "")
 	if err != nil {
 		return err
 	}
@@ -149,9 +192,6 @@ func (dt *DockerTarget) CheckContainer() error {
 
 func (dt *DockerTarget) CopyShadowProjectTo(src string) error {
 	dt.shadowPath = src
-	if err := dt.CheckContainer(); err != nil {
-		return err
-	}
 
 	tarPath := filepath.Join(src, "../shadow_tar")
 	err := utils.MakeTar(src, tarPath)
@@ -180,18 +220,8 @@ func (dt *DockerTarget) CopyShadowProjectTo(src string) error {
 }
 
 func (dt *DockerTarget) BuildExec(executor string, args []string, env map[string]string) ([]byte, []byte, error) {
-	enviorments := []string{
-		"CGO_ENABLED=0",
-		"GOOS=" + dt.platform,
-		"GOARCH=" + dt.arch,
-	}
-
-	for k, v := range env {
-		enviorments = append(enviorments, k+"="+v)
-	}
-
 	Insp.Print(Text("Command: "+executor, decorators.Cyan), Text("Args: "+strings.Join(args, " "), decorators.Cyan))
-	output, err := dt.ExecCommand(dt.temp, enviorments, executor, append([]string{"build", "-buildvcs=false"}, args...)...)
+	output, _, err := dt.Exec(executor, args, env)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -206,20 +236,34 @@ func (dt *DockerTarget) BuildExec(executor string, args []string, env map[string
 	return output, nil, nil
 }
 
+// Exec 在容器中的影子项目目录执行命令，标准输出与标准错误合并返回
+func (dt *DockerTarget) Exec(cmd string, args []string, env map[string]string) ([]byte, []byte, error) {
+	if dt.goCachePath != "" {
+		env = goCacheEnv(dt.goCachePath, dt.GoVersion(), env)
+	}
+	output, err := dt.ExecCommand(dt.workDir(), dt.Environ(env), cmd, args...)
+	return output, nil, err
+}
+
 func (dt *DockerTarget) CopyFileBack(src, dest string) error {
 	tarData, stat, err := dt.dc.CopyFromContainer(dt.ctx, dt.containerID, filepath.Join(dt.temp, "shadow_bin"))
 	if err != nil {
 		return err
 	}
 
-	tarPath := filepath.Join(dt.shadowPath, "../docker_return_tar")
+	//同一影子项目会被多个编译目标共用，返回文件按平台架构区分
+	suffix := dt.platform + "_" + dt.arch
+	tarPath := filepath.Join(dt.shadowPath, "../docker_return_tar_"+suffix)
 
 	err = utils.SaveFile(tarPath, tarData, true)
 	if err != nil {
 		return err
 	}
 
-	tarUnpackPath := filepath.Join(dt.shadowPath, "../docker_return")
+	tarUnpackPath := filepath.Join(dt.shadowPath, "../docker_return_"+suffix)
+	if err = os.RemoveAll(tarUnpackPath); err != nil {
+		return err
+	}
 	if err = os.Mkdir(tarUnpackPath, 0750); err != nil {
 		return err
 	}
@@ -232,7 +276,90 @@ func (dt *DockerTarget) CopyFileBack(src, dest string) error {
 	return utils.CopyFile(filepath.ToSlash(filepath.Join(tarUnpackPath, src)), dest, stat.Mode)
 }
 
+func (dt *DockerTarget) ExecShell(script string, env map[string]string) ([]byte, []byte, error) {
+	if dt.goCachePath != "" {
+		env = goCacheEnv(dt.goCachePath, dt.GoVersion(), env)
+	}
+	output, code, err := dt.ExecCommandStatus(dt.workDir(), dt.Environ(env), "sh", "-c", script)
+	if err != nil {
+		return output, nil, err
+	}
+	if code != 0 {
+		return output, nil, fmt.Errorf("exit status %d", code)
+	}
+	return output, nil, nil
+}
+
+// ImageDigest 编译容器所用镜像的ID，镜像更新后随之改变
+func (dt *DockerTarget) ImageDigest() (string, error) {
+	stats, err := dt.dc.ContainerInspect(dt.ctx, dt.containerID)
+	if err != nil {
+		return "", err
+	}
+	return stats.Image, nil
+}
+
+func (dt *DockerTarget) SetGoVersion(version string) {
+	dt.goVersion = version
+}
+
+// GoVersion 容器中的Go版本，未通过SetGoVersion设置时为go命令的版本
+func (dt *DockerTarget) GoVersion() string {
+	if dt.goVersion == "" {
+		output, err := dt.ExecCommand("", nil, "go", "version")
+		if err != nil {
+			return "unknown"
+		}
+		dt.goVersion = parseGoVersion(output)
+	}
+	return dt.goVersion
+}
+
+func (dt *DockerTarget) GoCacheInfo() (string, error) {
+	if err := dt.CheckContainer(); err != nil {
+		return "", err
+	}
+	if dt.goCachePath == "" {
+		return "", errors.New("go cache is off")
+	}
+	output, err := dt.ExecCommand("", nil, "du", "-sh", dt.goCachePath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("volume '%s' %s", dt.goCache, strings.TrimSpace(string(output))), nil
+}
+
+func (dt *DockerTarget) ClearGoCache() error {
+	if err := dt.CheckContainer(); err != nil {
+		return err
+	}
+	if dt.goCachePath == "" {
+		return errors.New("go cache is off")
+	}
+	base := shellquote.Join(dt.goCachePath)
+	//模块缓存中的文件是只读的
+	script := "chmod -R u+w " + base + " 2>/dev/null; rm -rf " + base
+	if dt.goCachePath == dockerGoCacheMount {
+		//缓存卷的挂载点本身无法删除，删除其中包括隐藏文件在内的所有内容
+		script = "chmod -R u+w " + base + " 2>/dev/null; find " + base + " -mindepth 1 -maxdepth 1 -exec rm -rf {} +"
+	}
+	output, code, err := dt.ExecCommandStatus("", nil, "sh", "-c", script)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("exit status %d %s", code, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 func (dt *DockerTarget) ExecCommand(dir string, env []string, cmd string, args ...string) ([]byte, error) {
+	output, _, err := dt.ExecCommandStatus(dir, env, cmd, args...)
+	return output, err
+}
+
+// ExecCommandStatus 在容器中执行命令，返回输出与退出码
+func (dt *DockerTarget) ExecCommandStatus(dir string, env []string, cmd string, args ...string) ([]byte, int, error) {
 	if dir == "" {
 		dir = "/"
 	}
@@ -247,33 +374,45 @@ func (dt *DockerTarget) ExecCommand(dir string, env []string, cmd string, args .
 		WorkingDir:   dir,
 	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// 执行命令并获取输出
 	resp, err := dt.dc.ContainerExecAttach(dt.ctx, createResp.ID, container.ExecAttachOptions{})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Close()
 	// 读取命令输出
 	output, err := io.ReadAll(resp.Reader)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return output, nil
+
+	inspect, err := dt.dc.ContainerExecInspect(dt.ctx, createResp.ID)
+	if err != nil {
+		return output, 0, err
+	}
+	return output, inspect.ExitCode, nil
 }
 
-func NewDockerTarget(host, container, image, temp, platform, arch string) *DockerTarget {
+// NewDockerTarget 创建Docker目标，goCache为缓存卷名称，为空时使用"bake-gocache"，为"off"时不使用缓存
+func NewDockerTarget(host, container, image, temp, goCache, platform, arch string) *DockerTarget {
 	dt := &DockerTarget{
 		BaseTarget:  NewBaseTarget(platform, arch),
 		host:        host,
 		containerID: container,
 		imageID:     image,
 		temp:        "/BAKE_DOCKER_TMP",
+		goCache:     "bake-gocache",
 	}
 	if temp != "" {
 		dt.temp = temp
 	}
+	if goCache == GoCacheOff {
+		dt.goCache = ""
+	} else if goCache != "" {
+		dt.goCache = goCache
+	}
 	return dt
 }
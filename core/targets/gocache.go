@@ -0,0 +1,47 @@
+package targets
+
+import (
+	"path"
+	"strings"
+)
+
+// GoCacheOff 关闭持久化Go缓存
+const GoCacheOff = "off"
+
+// GoCacheTarget 支持持久化Go编译缓存与模块缓存的编译目标
+type GoCacheTarget interface {
+	Target
+	// GoCacheInfo 返回缓存位置与占用
+	GoCacheInfo() (string, error)
+	// ClearGoCache 清除缓存
+	ClearGoCache() error
+	// SetGoVersion 设置编译目标实际使用的Go版本，缓存目录按该版本区分
+	SetGoVersion(version string)
+}
+
+// parseGoVersion 从"go version go1.22.5 linux/amd64"中解析出"go1.22.5"
+func parseGoVersion(output []byte) string {
+	fields := strings.Fields(string(output))
+	for _, field := range fields {
+		if strings.HasPrefix(field, "go1") || strings.HasPrefix(field, "devel") {
+			return field
+		}
+	}
+	return "unknown"
+}
+
+// goCacheEnv 在base下按Go版本区分GOCACHE与GOMODCACHE，env中已设置的变量不会被覆盖。
+// env通过GOTOOLCHAIN固定了工具链时使用该工具链的版本
+func goCacheEnv(base, version string, env map[string]string) map[string]string {
+	if toolchain, _, _ := strings.Cut(env["GOTOOLCHAIN"], "+"); strings.HasPrefix(toolchain, "go1") {
+		version = toolchain
+	}
+	merged := map[string]string{
+		"GOCACHE":    path.Join(base, version, "build"),
+		"GOMODCACHE": path.Join(base, version, "mod"),
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return merged
+}
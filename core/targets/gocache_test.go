@@ -0,0 +1,20 @@
+package targets
+
+import "testing"
+
+func TestGoCacheEnv(t *testing.T) {
+	for _, c := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{nil, "/cache/go1.21.0/build"},
+		{map[string]string{"GOTOOLCHAIN": "go1.22.5"}, "/cache/go1.22.5/build"},
+		{map[string]string{"GOTOOLCHAIN": "go1.22.5+auto"}, "/cache/go1.22.5/build"},
+		{map[string]string{"GOTOOLCHAIN": "local"}, "/cache/go1.21.0/build"},
+		{map[string]string{"GOCACHE": "/custom"}, "/custom"},
+	} {
+		if got := goCacheEnv("/cache", "go1.21.0", c.env)["GOCACHE"]; got != c.want {
+			t.Errorf("%v: GOCACHE %s, want %s", c.env, got, c.want)
+		}
+	}
+}
@@ -1,21 +1,86 @@
 package targets
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/B9O2/bake/utils"
-
-	Executor "github.com/B9O2/ExecManager"
 )
 
+// CleanEnvAllowlist 使用干净环境时默认从bake进程传递的环境变量
+var CleanEnvAllowlist = []string{"PATH", "HOME", "GOPATH", "GOCACHE"}
+
+// windowsEnv Windows上的go与C工具链运行所必需的环境变量
+var windowsEnv = []string{"SYSTEMROOT", "USERPROFILE", "LOCALAPPDATA", "APPDATA", "TEMP", "TMP", "COMSPEC", "PATHEXT"}
+
 type LocalTarget struct {
 	*BaseTarget
-	exec *Executor.Manager
+	clean bool
+	allow []string
+}
+
+// SetCleanEnv 子进程只继承allow与CleanEnvAllowlist中的环境变量
+func (lt *LocalTarget) SetCleanEnv(allow []string) {
+	lt.clean = true
+	lt.allow = append(append([]string{}, CleanEnvAllowlist...), allow...)
+	if runtime.GOOS == "windows" {
+		lt.allow = append(lt.allow, windowsEnv...)
+	}
+}
+
+// ProcessEnv 子进程的完整环境变量：继承的bake进程环境变量加上编译环境变量，不修改bake进程自身的环境
+func (lt *LocalTarget) ProcessEnv(env map[string]string) []string {
+	merged := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if lt.clean && !lt.allowed(k) {
+			continue
+		}
+		merged[k] = v
+	}
+	for _, kv := range lt.Environ(env) {
+		k, v, _ := strings.Cut(kv, "=")
+		merged[k] = v
+	}
+	environ := make([]string, 0, len(merged))
+	for k, v := range merged {
+		environ = append(environ, k+"="+v)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// Inherited 从bake进程继承的环境变量名，未使用干净环境时返回nil
+func (lt *LocalTarget) Inherited() []string {
+	if !lt.clean {
+		return nil
+	}
+	var names []string
+	for _, kv := range os.Environ() {
+		if k, _, _ := strings.Cut(kv, "="); lt.allowed(k) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (lt *LocalTarget) allowed(name string) bool {
+	for _, a := range lt.allow {
+		//Windows的环境变量名不区分大小写
+		if a == name || (runtime.GOOS == "windows" && strings.EqualFold(a, name)) {
+			return true
+		}
+	}
+	return false
 }
 
 func (lt *LocalTarget) InitAndConnect(string) error {
-	lt.exec = Executor.NewManager("LocalTargetExec")
 	return nil
 }
 
@@ -25,29 +90,33 @@ func (lt *LocalTarget) CopyShadowProjectTo(src string) error {
 }
 
 func (lt *LocalTarget) BuildExec(cmd string, args []string, env map[string]string) ([]byte, []byte, error) {
-	os.Setenv("CGO_ENABLED", "0")
-	os.Setenv("GOOS", lt.platform)
-	os.Setenv("GOARCH", lt.arch)
-
-	for k, v := range env {
-		os.Setenv(k, v)
-	}
+	return lt.Exec(cmd, args, env)
+}
 
-	pid, err := lt.exec.NewProcess(cmd, append([]string{"build"}, args...), lt.shadowPath)
-	if err != nil {
-		return nil, nil, err
-	}
+// Exec 以独立构造的环境变量执行命令，环境变量只传递给子进程
+func (lt *LocalTarget) Exec(cmd string, args []string, env map[string]string) ([]byte, []byte, error) {
+	c := exec.Command(cmd, args...)
+	c.Dir = lt.shadowPath
+	c.Env = lt.ProcessEnv(env)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c.Stdout, c.Stderr = stdout, stderr
+	err := c.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
 
-	var allStderr, allStdout []byte
-	for {
-		stdout, stderr, err := lt.exec.FetchAll(pid)
-		if err != nil {
-			break
-		}
-		allStdout = append(allStdout, stdout...)
-		allStderr = append(allStderr, stderr...)
+func (lt *LocalTarget) ExecShell(script string, env map[string]string) ([]byte, []byte, error) {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", script)
+	} else {
+		c = exec.Command("sh", "-c", script)
 	}
-	return allStdout, allStderr, nil
+	c.Dir = lt.shadowPath
+	c.Env = lt.ProcessEnv(env)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c.Stdout, c.Stderr = stdout, stderr
+	err := c.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
 }
 
 func (lt *LocalTarget) CopyFileBack(src, dest string) error {
@@ -55,6 +124,9 @@ func (lt *LocalTarget) CopyFileBack(src, dest string) error {
 }
 
 func (lt *LocalTarget) Info() string {
+	if lt.clean {
+		return "Local Build (clean env: " + strings.Join(lt.allow, ",") + ")"
+	}
 	return "Local Build"
 }
 func (lt *LocalTarget) Close() error { return nil }
@@ -0,0 +1,80 @@
+package targets
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// envMap 将"KEY=VALUE"列表转为map
+func envMap(environ []string) map[string]string {
+	m := map[string]string{}
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return m
+}
+
+func TestLocalTargetEnvIsolation(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	os.Unsetenv("GOFLAGS")
+
+	//第一个编译目标设置的GOFLAGS不能影响之后的编译目标与bake进程
+	first := NewLocalTarget("linux", "amd64")
+	stdout, _, err := first.Exec("go", []string{"env", "GOFLAGS"}, map[string]string{"GOFLAGS": "-mod=mod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(stdout)); got != "-mod=mod" {
+		t.Errorf("first pair GOFLAGS %q", got)
+	}
+	if _, ok := os.LookupEnv("GOFLAGS"); ok {
+		t.Error("GOFLAGS leaked into the bake process")
+	}
+
+	second := NewLocalTarget("linux", "arm64")
+	env := envMap(second.ProcessEnv(nil))
+	if v, ok := env["GOFLAGS"]; ok {
+		t.Errorf("GOFLAGS=%s leaked into the next pair", v)
+	}
+	if env["GOARCH"] != "arm64" || env["GOOS"] != "linux" {
+		t.Errorf("GOOS=%s GOARCH=%s", env["GOOS"], env["GOARCH"])
+	}
+	stdout, _, err = second.Exec("go", []string{"env", "GOFLAGS"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(stdout)); got != "" {
+		t.Errorf("next pair GOFLAGS %q", got)
+	}
+}
+
+func TestLocalTargetCleanEnv(t *testing.T) {
+	t.Setenv("BAKE_TEST_SECRET", "secret")
+	t.Setenv("BAKE_TEST_PASS", "pass")
+
+	lt := NewLocalTarget("linux", "arm64")
+	if env := envMap(lt.ProcessEnv(nil)); env["BAKE_TEST_SECRET"] != "secret" {
+		t.Error("default mode should inherit the bake environment")
+	}
+	if lt.Inherited() != nil {
+		t.Error("default mode reports inherited names")
+	}
+
+	lt.SetCleanEnv([]string{"BAKE_TEST_PASS"})
+	env := envMap(lt.ProcessEnv(map[string]string{"GOFLAGS": "-mod=mod"}))
+	if _, ok := env["BAKE_TEST_SECRET"]; ok {
+		t.Error("BAKE_TEST_SECRET passed in clean mode")
+	}
+	if env["BAKE_TEST_PASS"] != "pass" || env["GOFLAGS"] != "-mod=mod" || env["GOARCH"] != "arm64" {
+		t.Errorf("clean env %v", env)
+	}
+	if _, ok := os.LookupEnv("PATH"); ok && env["PATH"] == "" {
+		t.Error("PATH not passed in clean mode")
+	}
+	inherited := strings.Join(lt.Inherited(), " ")
+	if !strings.Contains(inherited, "BAKE_TEST_PASS") || strings.Contains(inherited, "BAKE_TEST_SECRET") {
+		t.Errorf("inherited %s", inherited)
+	}
+}
@@ -1,7 +1,9 @@
 package targets
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -13,12 +15,15 @@ import (
 
 type SSHTarget struct {
 	*BaseTarget
-	sshClient  *utils.SSHClient
-	temp       string
-	host       string
-	hashTag    string
-	port       int
-	authConfig *utils.SSHAuthConfig
+	sshClient   *utils.SSHClient
+	temp        string
+	host        string
+	hashTag     string
+	port        int
+	authConfig  *utils.SSHAuthConfig
+	goCache     string //远程主机上的缓存目录，可以以"~/"开头
+	goCachePath string
+	goVersion   string
 }
 
 func (st *SSHTarget) InitAndConnect(hashTag string) error {
@@ -41,13 +46,21 @@ func (st *SSHTarget) CopyShadowProjectTo(src string) error {
 }
 
 func (st *SSHTarget) BuildExec(cmd string, args []string, env map[string]string) ([]byte, []byte, error) {
-	envVars := []string{
-		"CGO_ENABLED=0",
-		fmt.Sprintf("GOOS=%s", st.platform),
-		fmt.Sprintf("GOARCH=%s", st.arch),
+	return st.Exec(cmd, args, env)
+}
+
+func (st *SSHTarget) Exec(cmd string, args []string, env map[string]string) ([]byte, []byte, error) {
+	if st.goCache != "" {
+		base, err := st.GoCachePath()
+		if err != nil {
+			return nil, nil, err
+		}
+		env = goCacheEnv(base, st.GoVersion(), env)
 	}
 
-	for k, v := range env {
+	var envVars []string
+	for _, kv := range st.Environ(env) {
+		k, v, _ := strings.Cut(kv, "=")
 		escapedValue := shellquote.Join(v)
 		envVars = append(envVars, fmt.Sprintf("%s=%s", k, escapedValue))
 	}
@@ -58,15 +71,85 @@ func (st *SSHTarget) BuildExec(cmd string, args []string, env map[string]string)
 		escapedArgs = append(escapedArgs, shellquote.Join(arg))
 	}
 
-	fullCmd := fmt.Sprintf("cd %s && %s %s build %s",
-		shellquote.Join(st.temp),
+	fullCmd := fmt.Sprintf("%s %s %s",
 		strings.Join(envVars, " "),
 		escapedCmd,
 		strings.Join(escapedArgs, " "))
+	//影子项目上传前在登录目录执行
+	if st.shadowPath != "" {
+		fullCmd = "cd " + shellquote.Join(st.temp) + " && " + fullCmd
+	}
 
 	return st.sshClient.ExecCommand(fullCmd)
 }
 
+func (st *SSHTarget) ExecShell(script string, env map[string]string) ([]byte, []byte, error) {
+	return st.Exec("sh", []string{"-c", script}, env)
+}
+
+// GoCachePath 解析远程主机上的缓存目录
+func (st *SSHTarget) GoCachePath() (string, error) {
+	if st.goCachePath != "" {
+		return st.goCachePath, nil
+	}
+	if st.goCache == "" {
+		return "", errors.New("go cache is off")
+	}
+	st.goCachePath = st.goCache
+	if st.goCache == "~" || strings.HasPrefix(st.goCache, "~/") {
+		stdout, stderr, err := st.sshClient.ExecCommand("cd && pwd")
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve remote home: %w %s", err, string(stderr))
+		}
+		st.goCachePath = path.Join(strings.TrimSpace(string(stdout)), strings.TrimPrefix(st.goCache, "~"))
+	}
+	return st.goCachePath, nil
+}
+
+func (st *SSHTarget) SetGoVersion(version string) {
+	st.goVersion = version
+}
+
+// GoVersion 远程主机上的Go版本，未通过SetGoVersion设置时为go命令的版本
+func (st *SSHTarget) GoVersion() string {
+	if st.goVersion == "" {
+		stdout, _, err := st.sshClient.ExecCommand("go version")
+		if err != nil {
+			return "unknown"
+		}
+		st.goVersion = parseGoVersion(stdout)
+	}
+	return st.goVersion
+}
+
+func (st *SSHTarget) GoCacheInfo() (string, error) {
+	base, err := st.GoCachePath()
+	if err != nil {
+		return "", err
+	}
+	stdout, _, err := st.sshClient.ExecCommand("du -sh " + shellquote.Join(base))
+	if err != nil {
+		return base + " (empty)", nil
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+func (st *SSHTarget) ClearGoCache() error {
+	base, err := st.GoCachePath()
+	if err != nil {
+		return err
+	}
+	if base == "/" || base == "" {
+		return errors.New("suspicious go cache path '" + base + "'")
+	}
+	//模块缓存中的文件是只读的
+	_, stderr, err := st.sshClient.ExecCommand("chmod -R u+w " + shellquote.Join(base) + " 2>/dev/null; rm -rf " + shellquote.Join(base))
+	if err != nil {
+		return fmt.Errorf("%w %s", err, string(stderr))
+	}
+	return nil
+}
+
 func (st *SSHTarget) CopyFileBack(src, dest string) error {
 	remotePath := filepath.Join(st.temp, src)
 	return st.sshClient.DownloadFile(remotePath, dest)
@@ -99,17 +182,23 @@ func (st *SSHTarget) Close() error {
 	return nil
 }
 
-// NewSSHTargetWithConfig 创建 SSH 目标
-func NewSSHTargetWithConfig(host string, port int, temp string, platform, arch string, config *utils.SSHAuthConfig) *SSHTarget {
+// NewSSHTargetWithConfig 创建 SSH 目标，goCache为远程缓存目录，为空时使用"~/.cache/bake-gocache"，为"off"时不使用缓存
+func NewSSHTargetWithConfig(host string, port int, temp, goCache string, platform, arch string, config *utils.SSHAuthConfig) *SSHTarget {
 	st := &SSHTarget{
 		BaseTarget: NewBaseTarget(platform, arch),
 		host:       host,
 		port:       port,
 		temp:       temp,
 		authConfig: config,
+		goCache:    "~/.cache/bake-gocache",
 	}
 	if st.temp == "" {
 		st.temp = "/tmp/BAKE_SSH_TMP"
 	}
+	if goCache == GoCacheOff {
+		st.goCache = ""
+	} else if goCache != "" {
+		st.goCache = goCache
+	}
 	return st
 }
@@ -1,13 +1,19 @@
 package targets
 
+import "sort"
+
 type Target interface {
 	Info() string
 	//InitAndConnect 连接远程编译目标
 	InitAndConnect(hashTag string) error
 	// CopyShadowProjectTo 复制影子项目路径到远程目标
 	CopyShadowProjectTo(src string) error //返回错误
-	// BuildExec 执行编译命令
+	// BuildExec 执行完整的编译命令(args中已包含build等子命令)
 	BuildExec(cmd string, args []string, env map[string]string) ([]byte, []byte, error)
+	// Exec 在远程目标的影子项目目录中执行命令，env会与默认编译环境变量合并
+	Exec(cmd string, args []string, env map[string]string) ([]byte, []byte, error)
+	// ExecShell 在远程目标的影子项目目录中通过shell执行脚本，退出码非0时返回错误
+	ExecShell(script string, env map[string]string) ([]byte, []byte, error)
 	// CopyFileBack 复制文件到本地指定输出目录
 	CopyFileBack(src, dest string) error
 	// Close 清理远程目标
@@ -25,3 +31,26 @@ func NewBaseTarget(platform, arch string) *BaseTarget {
 		arch:     arch,
 	}
 }
+
+// Environ 合并默认编译环境变量与env，env中的同名变量覆盖默认值。返回按变量名排序的"KEY=VALUE"列表
+func (bt *BaseTarget) Environ(env map[string]string) []string {
+	merged := map[string]string{
+		"CGO_ENABLED": "0",
+		"GOOS":        bt.platform,
+		"GOARCH":      bt.arch,
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	environ := make([]string, 0, len(keys))
+	for _, k := range keys {
+		environ = append(environ, k+"="+merged[k])
+	}
+	return environ
+}
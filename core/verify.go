@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/B9O2/bake/core/recipe/options"
+
+	"github.com/B9O2/Inspector/decorators"
+	. "github.com/B9O2/Inspector/templates/simple"
+	"github.com/alexmullins/zip"
+)
+
+// 匹配位置前后展示的字节数
+const findingContext = 24
+
+// Finding 产物中的一处禁止内容
+type Finding struct {
+	File    string //产物路径，ZIP中的文件为"ZIP路径:文件名"
+	Pattern string
+	Offset  int
+	Match   string
+	Context string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s@0x%x %q in %s", f.File, f.Offset, f.Pattern, f.Context)
+}
+
+// printable 将不可打印的字节显示为'.'
+func printable(data []byte) string {
+	var sb strings.Builder
+	for _, c := range data {
+		if c >= 0x20 && c < 0x7f {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+// ScanBytes 在data中查找所有禁止内容
+func ScanBytes(name string, data []byte, patterns []options.ForbiddenPattern) []Finding {
+	var findings []Finding
+	for _, p := range patterns {
+		for _, loc := range p.Regexp.FindAllIndex(data, -1) {
+			start, end := max(loc[0]-findingContext, 0), min(loc[1]+findingContext, len(data))
+			findings = append(findings, Finding{
+				File:    name,
+				Pattern: p.Name,
+				Offset:  loc[0],
+				Match:   printable(data[loc[0]:loc[1]]),
+				Context: printable(data[start:end]),
+			})
+		}
+	}
+	return findings
+}
+
+// ScanFile 检查单个产物文件
+func ScanFile(path string, patterns []options.ForbiddenPattern) ([]Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ScanBytes(path, data, patterns), nil
+}
+
+// ScanZip 检查ZIP中的每个文件，加密的ZIP使用password解密
+func ScanZip(path, password string, patterns []options.ForbiddenPattern) ([]Finding, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var findings []Finding
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.IsEncrypted() {
+			f.SetPassword(password)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%s: %w", path, f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%s: %w", path, f.Name, err)
+		}
+		findings = append(findings, ScanBytes(path+":"+f.Name, data, patterns)...)
+	}
+	return findings, nil
+}
+
+// VerifyOutput 检查编译产物，zipped为true时检查ZIP中的每个文件。发现禁止内容时返回错误
+func VerifyOutput(verify options.OptionVerify, path string, zipped bool, password string) error {
+	patterns, err := verify.Patterns()
+	if err != nil || len(patterns) == 0 {
+		return err
+	}
+	var findings []Finding
+	if zipped {
+		findings, err = ScanZip(path, password, patterns)
+	} else {
+		findings, err = ScanFile(path, patterns)
+	}
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		Insp.Print(Text("Verify Passed", decorators.Green), Path(path))
+		return nil
+	}
+	errs := []error{fmt.Errorf("%d forbidden matches in %s", len(findings), path)}
+	for _, f := range findings {
+		Insp.Print(Text("Forbidden", decorators.Red), Text(fmt.Sprintf("%s@0x%x", f.File, f.Offset), decorators.Yellow),
+			Text(f.Pattern, decorators.Magenta), Text(f.Context, decorators.Cyan))
+		errs = append(errs, errors.New(f.String()))
+	}
+	return errors.Join(errs...)
+}
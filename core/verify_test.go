@@ -0,0 +1,44 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/B9O2/bake/core/recipe/options"
+	"github.com/B9O2/bake/utils"
+)
+
+func TestVerifyOutput(t *testing.T) {
+	root := t.TempDir()
+	bin := filepath.Join(root, "app")
+	if err := os.WriteFile(bin, []byte("\x7fELF\x00\x00github.com/Google/uuid\x00main.main"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	verify := options.OptionVerify{ForbiddenStrings: []string{"google"}, IgnoreCase: true}
+	patterns, err := verify.Patterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := ScanFile(bin, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Offset != 17 || findings[0].Match != "Google" {
+		t.Fatalf("findings %v", findings)
+	}
+	if findings[0].Context != ".ELF..github.com/Google/uuid.main.main" {
+		t.Errorf("context %q", findings[0].Context)
+	}
+
+	dest := filepath.Join(root, "app.zip")
+	if err = utils.Zip(bin, dest, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyOutput(verify, dest, true, "secret"); err == nil {
+		t.Error("forbidden string in zip not reported")
+	}
+	if err = VerifyOutput(options.OptionVerify{ForbiddenRegexps: []string{`apple\.com`}}, dest, true, "secret"); err != nil {
+		t.Error(err)
+	}
+}
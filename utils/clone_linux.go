@@ -0,0 +1,32 @@
+//go:build linux
+
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile 使用FICLONE创建写时复制的文件副本，文件系统不支持时返回错误
+func cloneFile(source, destination string, mode os.FileMode) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(destination)
+		return err
+	}
+	return os.Chmod(destination, mode)
+}
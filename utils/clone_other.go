@@ -0,0 +1,12 @@
+//go:build !linux
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+func cloneFile(source, destination string, mode os.FileMode) error {
+	return errors.New("copy-on-write clone is not supported on this platform")
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignorePattern gitignore语法中的一条规则
+type ignorePattern struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreMatcher 按gitignore语法判断路径是否被忽略，后出现的规则优先
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// Add 添加一条gitignore语法的规则，base为规则所在文件相对根目录的路径
+func (im *IgnoreMatcher) Add(line, base string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	p := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	//包含非末尾的"/"时规则相对于所在目录
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if base != "" && base != "." {
+		if p.anchored {
+			line = path.Join(base, line)
+		} else {
+			line = path.Join(base, "**", line)
+		}
+		p.anchored = true
+	}
+	if line == "" {
+		return
+	}
+	p.pattern = line
+	im.patterns = append(im.patterns, p)
+}
+
+// AddFile 读取gitignore语法的文件，文件不存在时忽略
+func (im *IgnoreMatcher) AddFile(filePath, base string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		im.Add(scanner.Text(), base)
+	}
+	return scanner.Err()
+}
+
+// Match 判断相对根目录的路径rel是否被忽略
+func (im *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	ignored, _ := im.Check(rel, isDir)
+	return ignored
+}
+
+// Check 判断路径rel是否被忽略，matched表示是否有规则命中
+func (im *IgnoreMatcher) Check(rel string, isDir bool) (ignored, matched bool) {
+	if im == nil {
+		return false, false
+	}
+	rel = path.Clean(strings.ReplaceAll(rel, `\`, "/"))
+	for _, p := range im.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.match(rel) {
+			ignored, matched = !p.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// Len 规则数量
+func (im *IgnoreMatcher) Len() int {
+	return len(im.patterns)
+}
+
+func (p ignorePattern) match(rel string) bool {
+	if p.anchored {
+		return GlobMatch(p.pattern, rel)
+	}
+	//未锚定的规则可以匹配任意层级的文件名
+	return GlobMatch(p.pattern, path.Base(rel))
+}
+
+// GlobMatch 支持"**"的路径匹配，pattern与name均使用"/"分隔
+func GlobMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func NewIgnoreMatcher() *IgnoreMatcher {
+	return &IgnoreMatcher{}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	im := NewIgnoreMatcher()
+	for _, line := range []string{
+		"# comment",
+		".git/",
+		"*.log",
+		"!keep.log",
+		"/bake_bin/",
+		"testdata/**/large",
+		"node_modules",
+	} {
+		im.Add(line, "")
+	}
+	im.Add("*.tmp", "sub")
+
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{".git", true, true},
+		{".git", false, false},
+		{"a/b/debug.log", false, true},
+		{"keep.log", false, false},
+		{"bake_bin", true, true},
+		{"src/bake_bin", true, false},
+		{"testdata/large", false, true},
+		{"testdata/x/y/large", false, true},
+		{"web/node_modules", true, true},
+		{"sub/a.tmp", false, true},
+		{"sub/x/a.tmp", false, true},
+		{"a.tmp", false, false},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := im.Match(c.rel, c.isDir); got != c.ignored {
+			t.Errorf("Match(%q, %v) = %v, want %v", c.rel, c.isDir, got, c.ignored)
+		}
+	}
+}
@@ -10,12 +10,20 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"unsafe"
 )
 
+// CopyDirectory 复制目录。文件系统支持时使用写时复制(reflink)，否则逐个复制文件
 func CopyDirectory(source, destination string) error {
+	return CopyDirectoryFilter(source, destination, nil)
+}
+
+// CopyDirectoryFilter 复制目录，skip返回true的文件与目录(相对source的路径)不会被复制
+func CopyDirectoryFilter(source, destination string, skip func(rel string, isDir bool) bool) error {
+	cloneable := true
 	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -24,6 +32,12 @@ func CopyDirectory(source, destination string) error {
 		if err != nil {
 			return err
 		}
+		if skip != nil && relativePath != "." && skip(relativePath, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		destPath := filepath.Join(destination, relativePath)
 		if info.IsDir() {
@@ -32,6 +46,12 @@ func CopyDirectory(source, destination string) error {
 				return err
 			}
 		} else {
+			if cloneable {
+				if err = cloneFile(path, destPath, info.Mode()); err == nil {
+					return nil
+				}
+				cloneable = false //同一目录树位于同一文件系统，失败一次后不再尝试
+			}
 			err = CopyFile(path, destPath, info.Mode())
 			if err != nil {
 				return err
@@ -300,3 +320,94 @@ func JoinStrings[T fmt.Stringer](elems []T, sep string) string {
 	}
 	return strings.Join(strs, sep)
 }
+
+// ParseSize 解析"512MB"、"2G"、"1024"等形式的容量
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	multiple := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiple = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(n * float64(multiple)), nil
+}
+
+// FormatSize 以可读形式输出容量
+func FormatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	f := float64(size)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", f, units[i])
+}
+
+// ExpandHome 展开路径开头的"~"
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// IsBinary 与git相同，前8000字节中含有NUL时视为二进制内容
+func IsBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// SafeJoin 将相对路径rel拼接到root下，rel为绝对路径、跳出root或经由符号链接指向root之外时返回错误
+func SafeJoin(root, rel string) (string, error) {
+	if rel == "" || filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, `\`) {
+		return "", fmt.Errorf("path '%s' must be relative", rel)
+	}
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path '%s' escapes %s", rel, root)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	//检查已存在的上级目录没有通过符号链接指向root之外
+	dir := filepath.Dir(filepath.Join(realRoot, clean))
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if real != realRoot && !strings.HasPrefix(real, realRoot+string(filepath.Separator)) {
+				return "", fmt.Errorf("path '%s' escapes %s through a symlink", rel, root)
+			}
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Join(realRoot, clean), nil
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -17,3 +19,25 @@ func TestTar(t *testing.T) {
 		return
 	}
 }
+
+func TestSafeJoin(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skip(err)
+	}
+	for rel, ok := range map[string]bool{
+		"assets/logo.png":    true,
+		"a/../b.txt":         true,
+		"../escape.txt":      false,
+		"a/../../escape.txt": false,
+		"/etc/passwd":        false,
+		"link/file.txt":      false,
+		".":                  false,
+	} {
+		_, err := SafeJoin(root, rel)
+		if (err == nil) != ok {
+			t.Errorf("SafeJoin(%q): %v", rel, err)
+		}
+	}
+}
@@ -64,3 +64,46 @@ func Zip(src, dst, passwd string) error {
 	})
 	return err
 }
+
+// ZipFiles 将多个文件压缩到dst的根目录中
+func ZipFiles(files []string, dst, passwd string) error {
+	zipfile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer zipfile.Close()
+	archive := zip.NewWriter(zipfile)
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.Base(path)
+		header.Method = zip.Deflate
+		if passwd != "" {
+			header.SetPassword(passwd)
+		}
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	//写入中央目录失败时ZIP不完整
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return zipfile.Close()
+}